@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Fetch the files changed between two commits using Gitea's compare API.
+// The base may be any ancestor of head, or the head of another branch, in which case changes are compared to the merge base.
+func (s *Scanner) FetchChangedFiles(repository, base, head string) ([]string, error) {
+	reponame, err := pathEscapeRepository(repository)
+	if err != nil {
+		return nil, fmt.Errorf("comparing %s...%s in %s failed - %s", base, head, repository, err)
+	}
+
+	var compareResponse CompareResponse
+	_, err = s.plugin.RequestAPI(http.MethodGet, fmt.Sprintf("repos/%s/compare/%s...%s?files=true", reponame, pathEscapeRef(base), pathEscapeRef(head)), nil, &compareResponse)
+	if err != nil {
+		return nil, fmt.Errorf("comparing %s...%s in %s failed - %s", base, head, repository, err)
+	}
+
+	if len(compareResponse.Commits) < compareResponse.TotalCommits {
+		return nil, fmt.Errorf("comparing %s...%s in %s failed - incomplete result with %v of %v commits", base, head, repository, len(compareResponse.Commits), compareResponse.TotalCommits)
+	}
+
+	commits := make([]ModifiedFiles, len(compareResponse.Commits))
+	for i, commit := range compareResponse.Commits {
+		for _, file := range commit.Files {
+			switch file.Status {
+			case "added":
+				commits[i].Added = append(commits[i].Added, file.Filename)
+			case "removed", "deleted":
+				commits[i].Removed = append(commits[i].Removed, file.Filename)
+			default:
+				commits[i].Modified = append(commits[i].Modified, file.Filename)
+			}
+		}
+	}
+
+	return collectFiles(commits), nil
+}
+
+// collectPushFiles determines the files changed by a push.
+// Changes are determined using the compare API, so that force-pushes and large pushes are handled correctly.
+// For force-pushes, files changed by commits that were dropped from the branch are included as well.
+// New branches are compared to the repository's default branch.
+// If comparing fails, the commit list of the webhook payload is used instead.
+func (p *GiteaPlugin) collectPushFiles(webhook Webhook) []string {
+	repository := webhook.Repository.FullName
+	branch, isBranch := strings.CutPrefix(webhook.Ref, "refs/heads/")
+	if !isBranch || webhook.After == "" {
+		return collectFiles(webhook.ModifiedFiles())
+	}
+
+	base := webhook.Before
+	newBranch := base == "" || isZeroCommit(base)
+	if newBranch {
+		if branch == webhook.Repository.DefaultBranch {
+			return collectFiles(webhook.ModifiedFiles())
+		}
+
+		commitResponse, err := p.Scanner.FetchCommit(repository, webhook.Repository.DefaultBranch)
+		if err != nil {
+			p.Log.Warn(fmt.Sprintf("falling back to webhook payload for changed files - %s", err))
+			return collectFiles(webhook.ModifiedFiles())
+		}
+		if commitResponse == nil {
+			p.Log.Warn(fmt.Sprintf("falling back to webhook payload for changed files in %s - default branch not found", repository))
+			return collectFiles(webhook.ModifiedFiles())
+		}
+		base = commitResponse.Commit.ID
+	}
+
+	files, err := p.Scanner.FetchChangedFiles(repository, base, webhook.After)
+	if err != nil {
+		p.Log.Warn(fmt.Sprintf("falling back to webhook payload for changed files - %s", err))
+		return collectFiles(webhook.ModifiedFiles())
+	}
+
+	if !newBranch {
+		droppedFiles, err := p.Scanner.FetchChangedFiles(repository, webhook.After, base)
+		if err != nil {
+			p.Log.Warn(fmt.Sprintf("falling back to webhook payload for changed files - %s", err))
+			return collectFiles(webhook.ModifiedFiles())
+		}
+		files = mergeFiles(files, droppedFiles)
+	}
+
+	return files
+}
+
+// mergeFiles returns the union of two file lists.
+func mergeFiles(a, b []string) []string {
+	known := make(map[string]bool, len(a))
+	result := make([]string, 0, len(a)+len(b))
+	for _, list := range [][]string{a, b} {
+		for _, file := range list {
+			if !known[file] {
+				known[file] = true
+				result = append(result, file)
+			}
+		}
+	}
+	return result
+}
+
+// collectTagFiles determines the files changed since the tag preceding the specified tag.
+// If the tag is a semantic version, the preceding tag is the highest semantic version lower than the tag.
+// Otherwise, the tag that was created before the specified tag is used.
+// The boolean result is false if the changes could not be determined, e.g. because there is no preceding tag.
+func (p *GiteaPlugin) collectTagFiles(repository, tag, commit string) ([]string, bool) {
+	tags, err := p.Scanner.FetchTags(repository)
+	if err != nil {
+		p.Log.Warn(fmt.Sprintf("unable to determine changed files for tag %s - %s", tag, err))
+		return nil, false
+	}
+
+	previous := previousTag(tags, tag)
+	if previous == nil {
+		return nil, false
+	}
+
+	files, err := p.Scanner.FetchChangedFiles(repository, previous.Commit.Sha, commit)
+	if err != nil {
+		p.Log.Warn(fmt.Sprintf("unable to determine changed files for tag %s - %s", tag, err))
+		return nil, false
+	}
+
+	return files, true
+}
+
+// previousTag finds the tag preceding the specified tag.
+// Tags are expected to be ordered from newest to oldest.
+func previousTag(tags []TagResponse, tag string) *TagResponse {
+	if version, ok := ParseSemVer(tag); ok {
+		var previous *TagResponse
+		var previousVersion SemVer
+		for i, candidate := range tags {
+			candidateVersion, ok := ParseSemVer(candidate.Name)
+			if !ok || candidateVersion.Compare(version) >= 0 {
+				continue
+			}
+			if previous == nil || candidateVersion.Compare(previousVersion) > 0 {
+				previous = &tags[i]
+				previousVersion = candidateVersion
+			}
+		}
+		return previous
+	}
+
+	for i, candidate := range tags {
+		if candidate.Name == tag && i+1 < len(tags) {
+			return &tags[i+1]
+		}
+	}
+	return nil
+}
+
+// collectActionFiles determines the files changed between a base ref and the commit an action is executed on.
+// The boolean result is false if no base was specified or the changes could not be determined.
+func (p *GiteaPlugin) collectActionFiles(repository, base, commit string) ([]string, bool) {
+	if base == "" {
+		return nil, false
+	}
+
+	files, err := p.Scanner.FetchChangedFiles(repository, base, commit)
+	if err != nil {
+		p.Log.Warn(fmt.Sprintf("unable to determine changed files for action - %s", err))
+		return nil, false
+	}
+
+	return files, true
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/reeveci/reeve-lib/schema"
+)
+
+const CHATOPS_PREFIX = "/reeve"
+
+// ParseChatOpsCommands returns the actions requested by `/reeve run <action>` lines in a comment.
+func ParseChatOpsCommands(comment string) []string {
+	var actions []string
+	for _, line := range strings.Split(strings.ReplaceAll(comment, "\r\n", "\n"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != CHATOPS_PREFIX || fields[1] != "run" {
+			continue
+		}
+		actions = append(actions, fields[2:]...)
+	}
+	return actions
+}
+
+func (p *GiteaPlugin) handleIssueCommentWebhook(data []byte) error {
+	var webhook IssueCommentWebhook
+	err := json.Unmarshal(data, &webhook)
+	if err != nil {
+		return fmt.Errorf("error parsing webhook message %s", data)
+	}
+
+	if webhook.Action != "created" {
+		return nil
+	}
+
+	actions := ParseChatOpsCommands(webhook.Comment.Body)
+	if len(actions) == 0 {
+		return nil
+	}
+
+	repo := webhook.Repository
+	if !p.Filter.Match(repo) {
+		return nil
+	}
+
+	user := webhook.Comment.User.Login
+	allowed, err := p.HasWritePermission(repo.FullName, user)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		p.Log.Info(fmt.Sprintf("denying chat command of user %s in repository %s", user, repo.FullName))
+		p.ReactToComment(repo.FullName, webhook.Comment.ID, "-1")
+		p.ReplyToIssue(repo.FullName, webhook.Issue.Number, fmt.Sprintf("@%s you need write access to this repository in order to run actions.", user))
+		return nil
+	}
+
+	var ref, commit, commitMessage, base string
+	var commitDetails []CommitDetails
+	if webhook.IsPull || webhook.Issue.PullRequest != nil {
+		pull, err := p.FetchPullRequest(repo.FullName, webhook.Issue.Number)
+		if err != nil {
+			return err
+		}
+		if pull == nil {
+			return nil
+		}
+
+		if pull.Head.Repo.ID == pull.Base.Repo.ID {
+			ref = fullRefName(pull.Head.Ref, "branch")
+		} else {
+			ref = fmt.Sprintf("refs/pull/%v/head", pull.Number)
+		}
+		commit = pull.Head.Sha
+		base = pull.Base.Sha
+
+		headCommit, err := p.Scanner.FetchCommitDetails(repo.FullName, commit)
+		if err != nil {
+			return err
+		}
+		if headCommit != nil {
+			commitMessage = headCommit.Message
+			commitDetails = append(commitDetails, *headCommit)
+		}
+	} else {
+		commitResponse, err := p.Scanner.FetchCommit(repo.FullName, repo.DefaultBranch)
+		if err != nil {
+			return err
+		}
+		if commitResponse == nil {
+			return nil
+		}
+
+		ref = fullRefName(repo.DefaultBranch, "branch")
+		commit = commitResponse.Commit.ID
+		commitMessage = commitResponse.Commit.Message
+		commitDetails = append(commitDetails, commitResponse.Commit)
+	}
+
+	files, hasFiles := p.collectActionFiles(repo.FullName, base, commit)
+
+	triggers := make([]schema.Trigger, len(actions))
+	for i, action := range actions {
+		triggers[i] = NewActionTrigger(action, repo, ref, commit, commitMessage)
+		triggers[i]["pusher"] = user
+		if len(commitDetails) > 0 {
+			AddCommitDetails(triggers[i], commitDetails...)
+		}
+		if webhook.IsPull || webhook.Issue.PullRequest != nil {
+			triggers[i]["pullRequest"] = fmt.Sprint(webhook.Issue.Number)
+		}
+		if hasFiles {
+			triggers[i]["files"] = strings.Join(files, "\n")
+		}
+	}
+
+	p.Log.Info(fmt.Sprintf("user %s requested actions %s in repository %s", user, strings.Join(actions, ", "), repo.FullName))
+
+	err = p.API.NotifyTriggers(triggers)
+	if err != nil {
+		return fmt.Errorf("error notifying triggers - %s", err)
+	}
+
+	p.ReactToComment(repo.FullName, webhook.Comment.ID, "rocket")
+	return nil
+}
+
+// HasWritePermission reports whether a user is allowed to push to a repository.
+func (p *GiteaPlugin) HasWritePermission(repository, user string) (bool, error) {
+	reponame, err := pathEscapeRepository(repository)
+	if err != nil {
+		return false, fmt.Errorf("determining permission of %s for %s failed - %s", user, repository, err)
+	}
+
+	var permissionResponse PermissionResponse
+	status, err := p.RequestAPI(http.MethodGet, fmt.Sprintf("repos/%s/collaborators/%s/permission", reponame, url.PathEscape(user)), nil, &permissionResponse)
+	if status == http.StatusNotFound || status == http.StatusForbidden {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("determining permission of %s for %s failed - %s", user, repository, err)
+	}
+
+	switch permissionResponse.Permission {
+	case "write", "admin", "owner":
+		return true, nil
+
+	default:
+		return false, nil
+	}
+}
+
+// Fetch a pull request from a repository.
+// If the pull request was not found, response and error are nil.
+func (p *GiteaPlugin) FetchPullRequest(repository string, number int64) (*PullRequestResponse, error) {
+	reponame, err := pathEscapeRepository(repository)
+	if err != nil {
+		return nil, fmt.Errorf("fetching pull request #%v from %s failed - %s", number, repository, err)
+	}
+
+	var pullRequestResponse PullRequestResponse
+	status, err := p.RequestAPI(http.MethodGet, fmt.Sprintf("repos/%s/pulls/%v", reponame, number), nil, &pullRequestResponse)
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching pull request #%v from %s failed - %s", number, repository, err)
+	}
+
+	return &pullRequestResponse, nil
+}
+
+// ReactToComment adds a reaction to an issue or pull request comment.
+// Errors are logged only, since reactions are purely informational.
+func (p *GiteaPlugin) ReactToComment(repository string, comment int64, reaction string) {
+	reponame, err := pathEscapeRepository(repository)
+	if err == nil {
+		_, err = p.RequestAPI(http.MethodPost, fmt.Sprintf("repos/%s/issues/comments/%v/reactions", reponame, comment), map[string]string{"content": reaction}, nil)
+	}
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("error reacting to comment in repository %s - %s", repository, err))
+	}
+}
+
+// ReplyToIssue adds a comment to an issue or pull request.
+// Errors are logged only, since replies are purely informational.
+func (p *GiteaPlugin) ReplyToIssue(repository string, issue int64, body string) {
+	reponame, err := pathEscapeRepository(repository)
+	if err == nil {
+		_, err = p.RequestAPI(http.MethodPost, fmt.Sprintf("repos/%s/issues/%v/comments", reponame, issue), map[string]string{"body": body}, nil)
+	}
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("error replying to issue #%v in repository %s - %s", issue, repository, err))
+	}
+}
@@ -3,15 +3,21 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/reeveci/plugin-gitea/encryption"
 	"github.com/reeveci/reeve-lib/schema"
 )
 
 var CLIMethods = map[string]string{
-	"action":  "<action> [<search ...>] - execute action",
-	"encrypt": "<secret value> - encrypt variables for usage in pipeline file secrets",
-	"rescan":  "rescan all repositories",
+	"action":     "[--base=<ref>] <action> [<search ...>] - execute action",
+	"encrypt":    "[--repository=<owner/name>] [--branch=<pattern>] <secret value> - encrypt variables for usage in pipeline file secrets",
+	"publickey":  "print the public key for encrypting secrets locally using age",
+	"agescope":   "[--repository=<owner/name>] [--branch=<pattern>] - print the scope lines for binding secrets encrypted using age",
+	"reencrypt":  "<encrypted value> - re-encrypt a secret value using the primary secret key",
+	"rescan":     "rescan all repositories",
+	"deliveries": "list recent webhook deliveries",
+	"replay":     "<delivery id> - replay a recent webhook delivery",
 }
 
 func (p *GiteaPlugin) CLIMethod(method string, args []string) (string, error) {
@@ -22,6 +28,21 @@ func (p *GiteaPlugin) CLIMethod(method string, args []string) (string, error) {
 	case "encrypt":
 		return p.CLIEncrypt(args)
 
+	case "publickey":
+		return p.CLIPublicKey(args)
+
+	case "agescope":
+		return p.CLIAgeScope(args)
+
+	case "reencrypt":
+		return p.CLIReencrypt(args)
+
+	case "deliveries":
+		return p.CLIDeliveries(args)
+
+	case "replay":
+		return p.CLIReplay(args)
+
 	case "rescan":
 		err := p.API.NotifyMessages([]schema.Message{{Target: PLUGIN_NAME, Options: map[string]string{
 			"type":      "operation",
@@ -38,6 +59,12 @@ func (p *GiteaPlugin) CLIMethod(method string, args []string) (string, error) {
 }
 
 func (p *GiteaPlugin) CLIAction(args []string) (string, error) {
+	var base string
+	if len(args) > 0 && strings.HasPrefix(args[0], "--base=") {
+		base = strings.TrimPrefix(args[0], "--base=")
+		args = args[1:]
+	}
+
 	var action string
 	if len(args) > 0 {
 		action = args[0]
@@ -51,6 +78,7 @@ func (p *GiteaPlugin) CLIAction(args []string) (string, error) {
 		"type":   "action",
 		"action": action,
 		"search": strings.Join(args[1:], " "),
+		"base":   base,
 	}}})
 
 	if err != nil {
@@ -60,13 +88,124 @@ func (p *GiteaPlugin) CLIAction(args []string) (string, error) {
 }
 
 func (p *GiteaPlugin) CLIEncrypt(args []string) (string, error) {
+	scope, args, err := p.parseScopeOptions(args, 1)
+	if err != nil {
+		return "", err
+	}
 	if len(args) != 1 {
 		return "", fmt.Errorf("encrypt expects one argument but got %v", len(args))
 	}
 
-	encrypted, err := encryption.EncryptSecret(p.SecretKey, args[0])
+	encrypted, err := p.Keyring.Encrypt(args[0], scope)
 	if err != nil {
 		return "", fmt.Errorf("encryption failed - %s", err)
 	}
 	return encrypted, nil
 }
+
+func (p *GiteaPlugin) CLIAgeScope(args []string) (string, error) {
+	scope, args, err := p.parseScopeOptions(args, 0)
+	if err != nil {
+		return "", err
+	}
+	if len(args) != 0 {
+		return "", fmt.Errorf("agescope expects no arguments but got %v", len(args))
+	}
+	if scope.IsZero() {
+		return "", fmt.Errorf("no scope was specified")
+	}
+
+	return encryption.FormatAgeScope(scope), nil
+}
+
+// parseScopeOptions parses the leading `--repository` and `--branch` options, keeping at least the given number of arguments.
+// The repository is resolved to its ID, so that values cannot be used by another repository created under the same name.
+func (p *GiteaPlugin) parseScopeOptions(args []string, keep int) (encryption.Scope, []string, error) {
+	var scope encryption.Scope
+	for len(args) > keep && strings.HasPrefix(args[0], "--") {
+		switch {
+		case strings.HasPrefix(args[0], "--repository="):
+			scope.Repository = strings.TrimPrefix(args[0], "--repository=")
+		case strings.HasPrefix(args[0], "--branch="):
+			scope.Branch = strings.TrimPrefix(args[0], "--branch=")
+		default:
+			return scope, nil, fmt.Errorf("unknown option %s", args[0])
+		}
+		args = args[1:]
+	}
+
+	if scope.Branch != "" {
+		if _, err := GlobToRegexp(scope.Branch); err != nil {
+			return scope, nil, fmt.Errorf("invalid branch pattern \"%s\" - %s", scope.Branch, err)
+		}
+	}
+	if scope.Repository != "" {
+		repo, err := p.Scanner.FetchRepository(scope.Repository)
+		if err != nil {
+			return scope, nil, err
+		}
+		if repo == nil {
+			return scope, nil, fmt.Errorf("repository %s not found", scope.Repository)
+		}
+		scope.Repository = strings.ToLower(repo.FullName)
+		scope.RepositoryID = repo.ID
+	}
+
+	return scope, args, nil
+}
+
+func (p *GiteaPlugin) CLIPublicKey(args []string) (string, error) {
+	if len(args) != 0 {
+		return "", fmt.Errorf("publickey expects no arguments but got %v", len(args))
+	}
+
+	return p.Keyring.Recipient()
+}
+
+func (p *GiteaPlugin) CLIReencrypt(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("reencrypt expects one argument but got %v", len(args))
+	}
+
+	encrypted, err := p.Keyring.Reencrypt(args[0])
+	if err != nil {
+		return "", fmt.Errorf("re-encryption failed - %s", err)
+	}
+	return encrypted, nil
+}
+
+func (p *GiteaPlugin) CLIDeliveries(args []string) (string, error) {
+	if len(args) != 0 {
+		return "", fmt.Errorf("deliveries expects no arguments but got %v", len(args))
+	}
+
+	deliveries := p.Deliveries.List()
+	if len(deliveries) == 0 {
+		return "no webhook deliveries recorded", nil
+	}
+
+	lines := make([]string, len(deliveries))
+	for i, delivery := range deliveries {
+		lines[i] = fmt.Sprintf("%s  %s  %s", delivery.ID, delivery.Time.Format(time.RFC3339), delivery.Event)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (p *GiteaPlugin) CLIReplay(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("replay expects one argument but got %v", len(args))
+	}
+
+	if _, ok := p.Deliveries.Get(args[0]); !ok {
+		return "", fmt.Errorf("unknown webhook delivery %s", args[0])
+	}
+
+	err := p.API.NotifyMessages([]schema.Message{{Target: PLUGIN_NAME, Options: map[string]string{
+		"type":     "replay",
+		"delivery": args[0],
+	}}})
+	if err != nil {
+		return "", fmt.Errorf("queueing replay failed - %s", err)
+	}
+	return "accepted", nil
+}
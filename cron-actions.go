@@ -12,7 +12,7 @@ import (
 func NewCronActions(plugin *GiteaPlugin) *CronActions {
 	a := &CronActions{
 		plugin: plugin,
-		repos:  make(map[string]cronActionSet),
+		repos:  make(map[int64]cronActionSet),
 	}
 	return a
 }
@@ -20,16 +20,18 @@ func NewCronActions(plugin *GiteaPlugin) *CronActions {
 type CronActions struct {
 	lock   sync.Mutex
 	plugin *GiteaPlugin
-	repos  map[string]cronActionSet
+	repos  map[int64]cronActionSet
 }
 
-func (a *CronActions) UpdateRules(repository string, rules CronRuleset) {
+func (a *CronActions) UpdateRules(repositoryID int64, repository string, rules CronRuleset) {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
-	prev, found := a.repos[repository]
+	prev, found := a.repos[repositoryID]
 	if found {
 		if prev.rules.compare(rules) {
+			prev.repository = repository
+			a.repos[repositoryID] = prev
 			return
 		}
 
@@ -40,7 +42,7 @@ func (a *CronActions) UpdateRules(repository string, rules CronRuleset) {
 	if len(rules) == 0 {
 		if found {
 			a.plugin.Log.Info(fmt.Sprintf("clearing cron triggers for repository %s", repository))
-			delete(a.repos, repository)
+			delete(a.repos, repositoryID)
 		}
 		return
 	}
@@ -48,7 +50,7 @@ func (a *CronActions) UpdateRules(repository string, rules CronRuleset) {
 	a.plugin.Log.Info(fmt.Sprintf("updating cron triggers for repository %s", repository))
 
 	handler := crontab.New()
-	a.repos[repository] = cronActionSet{handler, rules}
+	a.repos[repositoryID] = cronActionSet{handler, repository, rules}
 
 	for cron, actions := range rules {
 		actionNames := make([]string, 0, len(actions))
@@ -74,6 +76,21 @@ func (a *CronActions) UpdateRules(repository string, rules CronRuleset) {
 	}
 }
 
+// RenameRepository updates the name of a repository whose cron triggers are already registered.
+func (a *CronActions) RenameRepository(repositoryID int64, repository string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	set, found := a.repos[repositoryID]
+	if !found || set.repository == repository {
+		return
+	}
+
+	a.plugin.Log.Info(fmt.Sprintf("moving cron triggers from repository %s to %s", set.repository, repository))
+	set.repository = repository
+	a.repos[repositoryID] = set
+}
+
 func (a *CronActions) Close() {
 	a.lock.Lock()
 	defer a.lock.Unlock()
@@ -95,8 +112,9 @@ func (a *CronActions) sendMessages(logMessage string, messages []schema.Message)
 }
 
 type cronActionSet struct {
-	handler *crontab.Crontab
-	rules   CronRuleset
+	handler    *crontab.Crontab
+	repository string
+	rules      CronRuleset
 }
 
 func NewCronRuleset() CronRuleset {
@@ -2,6 +2,9 @@ package main
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/reeveci/reeve-lib/schema"
@@ -18,6 +21,7 @@ func (p *GiteaPlugin) Discover(trigger schema.Trigger) ([]schema.Pipeline, error
 	repositoryURL := trigger["repositoryURL"]
 	cloneURL := trigger["cloneURL"]
 	defaultBranch := trigger["defaultBranch"]
+	releaseName := trigger["releaseName"]
 	rawFiles, hasFiles := trigger["files"]
 	files := strings.Split(rawFiles, "\n")
 
@@ -38,24 +42,50 @@ func (p *GiteaPlugin) Discover(trigger schema.Trigger) ([]schema.Pipeline, error
 	}
 
 	switch triggerType {
-	case "push", "action":
+	case "push", "action", "create", "delete", "release":
 
 	default:
 		return nil, fmt.Errorf("invalid git trigger - unknown trigger type %s", triggerType)
 	}
 
+	if isZeroCommit(commit) {
+		return nil, nil
+	}
+
 	if _, err := pathEscapeRepository(repository); err != nil {
 		return nil, fmt.Errorf("invalid git trigger - %s", err)
 	}
 
+	if ok, err := p.Scanner.FilterRepository(repository); !ok || err != nil {
+		return nil, err
+	}
+
+	commitMessages := parseCommitMessages(trigger)
+
 	facts := map[string]schema.Fact{
-		"trigger":    {triggerType},
-		"action":     nil,
-		"ref":        {ref},
-		"branch":     nil,
-		"file":       nil,
-		"tag":        nil,
-		"repository": {repository},
+		"trigger":          {triggerType},
+		"action":           nil,
+		"ref":              {ref},
+		"branch":           nil,
+		"file":             nil,
+		"tag":              nil,
+		"release":          nil,
+		"prerelease":       nil,
+		"draft":            nil,
+		"semver":           nil,
+		"semverMajor":      nil,
+		"semverMinor":      nil,
+		"semverPatch":      nil,
+		"semverPrerelease": nil,
+		"component":        nil,
+		"directory":        nil,
+		"author":           splitTriggerList(trigger["authors"]),
+		"committer":        splitTriggerList(trigger["committers"]),
+		"pusher":           splitTriggerList(trigger["pusher"]),
+		"commitMessage":    commitMessages,
+		"owner":            splitTriggerList(trigger["owner"]),
+		"visibility":       splitTriggerList(trigger["visibility"]),
+		"repository":       {repository},
 	}
 
 	if triggerType == "action" {
@@ -66,22 +96,93 @@ func (p *GiteaPlugin) Discover(trigger schema.Trigger) ([]schema.Pipeline, error
 		facts["action"] = schema.Fact{action}
 	}
 
+	trailers := collectTrailers(commitMessages)
+	for key, values := range trailers {
+		facts[TRAILER_FACT_PREFIX+key] = values
+	}
+
+	shortCommit := commit
+	if len(shortCommit) > 10 {
+		shortCommit = shortCommit[:10]
+	}
+
+	var branchName, tagName string
+	if strings.HasPrefix(ref, "refs/heads/") {
+		branchName = strings.TrimPrefix(ref, "refs/heads/")
+	}
+	if strings.HasPrefix(ref, "refs/tags/") {
+		tagName = strings.TrimPrefix(ref, "refs/tags/")
+	}
+
+	env := make(map[string]schema.Env)
+	addBuiltinEnv(env, map[string]string{
+		"REEVE_GIT_REPOSITORY":   repository,
+		"REEVE_GIT_REF":          ref,
+		"REEVE_GIT_COMMIT":       commit,
+		"REEVE_GIT_SHORT_COMMIT": shortCommit,
+		"REEVE_GIT_BRANCH":       branchName,
+		"REEVE_GIT_TAG":          tagName,
+		"REEVE_GIT_TRIGGER":      triggerType,
+		"REEVE_GIT_ACTION":       action,
+		"REEVE_GIT_PR":           trigger["pullRequest"],
+	})
+
+	if triggerType == "release" {
+		if !strings.HasPrefix(ref, "refs/tags/") {
+			return nil, fmt.Errorf("invalid git trigger - release ref %s is not a tag", ref)
+		}
+
+		if releaseAction := trigger["releaseAction"]; releaseAction != "" {
+			facts["trigger"] = append(facts["trigger"], releaseAction)
+		}
+		facts["release"] = schema.Fact{releaseName}
+		facts["prerelease"] = schema.Fact{trigger["releasePrerelease"]}
+		facts["draft"] = schema.Fact{trigger["releaseDraft"]}
+
+		addBuiltinEnv(env, map[string]string{
+			"REEVE_RELEASE_NAME":   releaseName,
+			"REEVE_RELEASE_BODY":   trigger["releaseBody"],
+			"REEVE_RELEASE_ASSETS": trigger["releaseAssets"],
+		})
+	}
+
 	if strings.HasPrefix(ref, "refs/heads/") {
 		facts["branch"] = schema.Fact{strings.TrimPrefix(ref, "refs/heads/")}
 		if triggerType == "push" {
 			facts["trigger"] = append(facts["trigger"], "commit")
 		}
+	}
 
-		if hasFiles {
-			facts["file"] = files
-		}
+	if hasFiles {
+		facts["file"] = files
 	}
 
 	if strings.HasPrefix(ref, "refs/tags/") {
-		facts["tag"] = schema.Fact{strings.TrimPrefix(ref, "refs/tags/")}
+		tag := strings.TrimPrefix(ref, "refs/tags/")
+		facts["tag"] = schema.Fact{tag}
 		if triggerType == "push" {
 			facts["trigger"] = append(facts["trigger"], "tag")
 		}
+
+		if version, ok := ParseSemVer(tag); ok {
+			facts["semver"] = schema.Fact{version.String()}
+			facts["semverMajor"] = schema.Fact{strconv.FormatUint(version.Major, 10)}
+			facts["semverMinor"] = schema.Fact{strconv.FormatUint(version.Minor, 10)}
+			facts["semverPatch"] = schema.Fact{strconv.FormatUint(version.Patch, 10)}
+			facts["semverPrerelease"] = schema.Fact{version.Prerelease}
+			if facts["prerelease"] == nil {
+				facts["prerelease"] = schema.Fact{strconv.FormatBool(version.Prerelease != "")}
+			}
+
+			addBuiltinEnv(env, map[string]string{
+				"REEVE_SEMVER":            version.String(),
+				"REEVE_SEMVER_MAJOR":      strconv.FormatUint(version.Major, 10),
+				"REEVE_SEMVER_MINOR":      strconv.FormatUint(version.Minor, 10),
+				"REEVE_SEMVER_PATCH":      strconv.FormatUint(version.Patch, 10),
+				"REEVE_SEMVER_PRERELEASE": version.Prerelease,
+				"REEVE_SEMVER_BUILD":      version.Build,
+			})
+		}
 	}
 
 	defaultConditions := map[string]schema.Condition{
@@ -112,44 +213,117 @@ func (p *GiteaPlugin) Discover(trigger schema.Trigger) ([]schema.Pipeline, error
 		}
 		triggerDescription = fmt.Sprintf("%s: %s", triggerType, ref)
 
+	case "create", "delete":
+		if strings.HasPrefix(ref, "refs/heads/") {
+			triggerHeadline = fmt.Sprintf("[%s branch %s]", triggerType, strings.TrimPrefix(ref, "refs/heads/"))
+		} else if strings.HasPrefix(ref, "refs/tags/") {
+			triggerHeadline = fmt.Sprintf("[%s tag %s]", triggerType, strings.TrimPrefix(ref, "refs/tags/"))
+		} else {
+			triggerHeadline = fmt.Sprintf("[%s %s]", triggerType, ref)
+		}
+		triggerDescription = fmt.Sprintf("%s: %s", triggerType, ref)
+
+	case "release":
+		name := releaseName
+		if strings.TrimSpace(name) == "" {
+			name = strings.TrimPrefix(ref, "refs/tags/")
+		}
+		triggerHeadline = fmt.Sprintf("[release %s]", name)
+		triggerDescription = fmt.Sprintf("%s: %s", triggerType, ref)
+
 	case "action":
 		triggerHeadline = fmt.Sprintf("[action %s]", action)
 		triggerDescription = fmt.Sprintf("%s: %s", triggerType, action)
+		if pullRequest := trigger["pullRequest"]; pullRequest != "" {
+			triggerDescription += fmt.Sprintf(" ([pull request #%s](%s/pulls/%s))", pullRequest, repositoryURL, pullRequest)
+		}
 
 	default:
 		triggerHeadline = fmt.Sprintf("[%s]", triggerType)
 		triggerDescription = triggerType
 	}
-	shortCommit := commit
-	if len(shortCommit) > 10 {
-		shortCommit = shortCommit[:10]
-	}
 	description := fmt.Sprintf(
 		`> [%s](%s) | [%s](%s)\
 > %s
 
 `, repository, repositoryURL, shortCommit, repositoryURL+"/src/commit/"+commit, triggerDescription)
 
-	env := make(map[string]schema.Env)
-	pipelineDefs := make([]*schema.PipelineDefinition, 0)
+	result := NewDiscoverResult(env)
 
-	err := p.Scanner.ScanRepository(repository, commit, NewDiscoverScanner(p, repository, commit, env, &pipelineDefs, defaultConditions))
+	err := p.Scanner.ScanRepository(repository, commit, NewDiscoverScanner(p, repository, ref, commit, result, defaultConditions))
 	if err != nil {
 		return nil, err
 	}
 
+	if len(result.Pipelines) == 0 {
+		return nil, nil
+	}
+
+	addMissingTrailerFacts(facts, result.Pipelines)
+
+	facts["protected"] = schema.Fact{strconv.FormatBool(p.isProtectedRef(repository, ref))}
+	verified := p.isVerifiedCommit(repository, commit)
+	facts["verified"] = schema.Fact{strconv.FormatBool(verified)}
+	if !verified && p.WithholdUnverifiedSecrets {
+		p.Log.Info(fmt.Sprintf("withholding secrets from pipelines in %s for unverified commit %s", repository, commit))
+		result.WithholdSecrets()
+	}
+
+	if hasFiles {
+		facts["component"] = collectComponents(files, result.Components)
+		facts["directory"] = collectDirectories(files)
+
+		for _, filter := range result.PathFilters {
+			facts[filter.Key] = schema.Fact{strconv.FormatBool(filter.Check(files))}
+		}
+	}
+
 	env["__GIT_TOKEN"] = schema.Env{
 		Value:    p.Token,
 		Priority: 0,
 		Secret:   true,
 	}
 
-	pipelines := make([]schema.Pipeline, len(pipelineDefs))
-	for i, def := range pipelineDefs {
+	if triggerType == "push" {
+		skipped := collectSkippedPipelines(commitMessages, trailers)
+		definitions := make([]*schema.PipelineDefinition, 0, len(result.Pipelines))
+		for _, def := range result.Pipelines {
+			if skipped[strings.ToLower(def.Name)] {
+				p.Log.Debug(fmt.Sprintf("skipping pipeline %s in repository %s as requested by commit message", def.Name, repository))
+				continue
+			}
+			definitions = append(definitions, def)
+		}
+		result.Pipelines = definitions
+	}
+
+	if len(result.FailedSecrets) > 0 {
+		definitions := make([]*schema.PipelineDefinition, 0, len(result.Pipelines))
+		for _, def := range result.Pipelines {
+			if missing := missingSecrets(def, result.EnvFor(def.Name), result.FailedSecrets); len(missing) > 0 {
+				p.Log.Warn(fmt.Sprintf("suppressing pipeline %s in repository %s - missing secrets %s", def.Name, repository, strings.Join(missing, ", ")))
+				continue
+			}
+			definitions = append(definitions, def)
+		}
+		result.Pipelines = definitions
+
+		failed := make([]string, 0, len(result.FailedSecrets))
+		for name := range result.FailedSecrets {
+			failed = append(failed, "`"+name+"`")
+		}
+		sort.Strings(failed)
+		description += fmt.Sprintf("> **Warning:** The following secrets could not be decrypted and are unavailable: %s\n\n", strings.Join(failed, ", "))
+	}
+
+	pipelines := make([]schema.Pipeline, len(result.Pipelines))
+	for i, def := range result.Pipelines {
+		pipelineEnv := result.EnvFor(def.Name)
+
 		pipelines[i] = schema.Pipeline{
 			PipelineDefinition: *def,
 
-			Env:            env,
+			Env:            pipelineEnv,
 			Facts:          facts,
 			TaskDomains:    p.TaskDomains,
 			TrustedDomains: p.TrustedDomains,
@@ -167,6 +341,9 @@ func (p *GiteaPlugin) Discover(trigger schema.Trigger) ([]schema.Pipeline, error
 				},
 			},
 		}
+		for key, param := range SecretFileParams(result.SecretFiles, pipelineEnv) {
+			pipelines[i].Setup.Params[key] = param
+		}
 
 		if strings.TrimSpace(pipelines[i].Headline) == "" {
 			pipelines[i].Headline = triggerHeadline
@@ -176,3 +353,85 @@ func (p *GiteaPlugin) Discover(trigger schema.Trigger) ([]schema.Pipeline, error
 
 	return pipelines, nil
 }
+
+// missingSecrets returns the failed secrets that are referenced by a pipeline's steps or conditions, but missing from its environment.
+func missingSecrets(pipeline *schema.PipelineDefinition, env map[string]schema.Env, failed map[string]string) []string {
+	referenced := make(map[string]bool)
+	addConditions := func(when map[string]schema.Condition) {
+		for _, condition := range when {
+			for _, key := range append(append([]string{}, condition.IncludeEnv...), condition.ExcludeEnv...) {
+				referenced[key] = true
+			}
+		}
+	}
+
+	addConditions(pipeline.When)
+	for _, step := range pipeline.Steps {
+		addConditions(step.When)
+		for _, key := range step.GetEnv() {
+			referenced[key] = true
+		}
+	}
+
+	var result []string
+	for name := range failed {
+		if _, ok := env[name]; !ok && referenced[name] {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// collectComponents determines the components affected by a list of files.
+// If no component is affected, the result contains an empty string, so that conditions on components do not match.
+func collectComponents(files []string, components map[string][]*regexp.Regexp) schema.Fact {
+	result := make(schema.Fact, 0, len(components))
+	for name, patterns := range components {
+		for _, file := range files {
+			if MatchAny(patterns, file) {
+				result = append(result, name)
+				break
+			}
+		}
+	}
+	if len(result) == 0 {
+		return schema.Fact{""}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// collectDirectories determines the top-level directories affected by a list of files.
+// Files in the repository's root directory are represented by an empty string.
+func collectDirectories(files []string) schema.Fact {
+	known := make(map[string]bool)
+	result := make(schema.Fact, 0)
+	for _, file := range files {
+		var directory string
+		if i := strings.Index(file, "/"); i >= 0 {
+			directory = file[:i]
+		}
+		if !known[directory] {
+			known[directory] = true
+			result = append(result, directory)
+		}
+	}
+	if len(result) == 0 {
+		return schema.Fact{""}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// addBuiltinEnv adds environment variables provided by the plugin.
+// These are added before scanning the repository, so that repository variables may override them.
+func addBuiltinEnv(env map[string]schema.Env, values map[string]string) {
+	for key, value := range values {
+		env[key] = schema.Env{
+			Value:    value,
+			Priority: 0,
+			Secret:   false,
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PATH_FILTER_FACT_PREFIX is the prefix of the facts generated for path filters.
+const PATH_FILTER_FACT_PREFIX = "paths:"
+
+// PathPatterns holds the `paths` and `paths-ignore` shorthands of a `when` block.
+type PathPatterns struct {
+	Paths       []string
+	PathsIgnore []string
+}
+
+func (p PathPatterns) Empty() bool {
+	return len(p.Paths) == 0 && len(p.PathsIgnore) == 0
+}
+
+// PathFilters holds the path shorthands of a pipeline and its steps.
+// Steps are indexed like the pipeline's steps.
+type PathFilters struct {
+	Pipeline PathPatterns
+	Steps    []PathPatterns
+}
+
+// PathFilter is a compiled `paths` / `paths-ignore` shorthand.
+type PathFilter struct {
+	Key         string
+	Paths       []*regexp.Regexp
+	PathsIgnore []*regexp.Regexp
+}
+
+// Check reports whether a list of changed files passes the filter.
+// Files matching `paths-ignore` are disregarded. If `paths` is specified, one of the remaining files must match it,
+// otherwise there must be any remaining file.
+func (f PathFilter) Check(files []string) bool {
+	for _, file := range files {
+		if file == "" || MatchAny(f.PathsIgnore, file) {
+			continue
+		}
+		if len(f.Paths) == 0 || MatchAny(f.Paths, file) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Document) UnmarshalYAML(node *yaml.Node) error {
+	node = resolveAlias(node)
+
+	// `when` blocks may be shared using anchors and aliases, but path patterns are removed from a block when they are extracted,
+	// so the patterns are remembered per block
+	extracted := make(map[*yaml.Node]PathPatterns)
+	extract := func(when *yaml.Node) (PathPatterns, error) {
+		if patterns, ok := extracted[when]; ok {
+			return patterns, nil
+		}
+		patterns, err := extractPathPatterns(when)
+		if err != nil {
+			return PathPatterns{}, err
+		}
+		extracted[when] = patterns
+		return patterns, nil
+	}
+
+	if node.Kind == yaml.MappingNode {
+		if when := mappingValue(node, "when"); when != nil {
+			var err error
+			if d.PathFilters.Pipeline, err = extract(when); err != nil {
+				return err
+			}
+		}
+
+		if steps := mappingValue(node, "steps"); steps != nil && steps.Kind == yaml.SequenceNode {
+			d.PathFilters.Steps = make([]PathPatterns, len(steps.Content))
+			for i, step := range steps.Content {
+				step = resolveAlias(step)
+				if step.Kind != yaml.MappingNode {
+					continue
+				}
+				if when := mappingValue(step, "when"); when != nil {
+					var err error
+					if d.PathFilters.Steps[i], err = extract(when); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	type rawDocument Document
+	return node.Decode((*rawDocument)(d))
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return resolveAlias(node.Content[i+1])
+		}
+	}
+	return nil
+}
+
+// extractPathPatterns removes the `paths` and `paths-ignore` entries from a `when` block,
+// since they cannot be decoded as conditions.
+func extractPathPatterns(when *yaml.Node) (result PathPatterns, err error) {
+	if when.Kind != yaml.MappingNode {
+		return
+	}
+
+	content := make([]*yaml.Node, 0, len(when.Content))
+	for i := 0; i+1 < len(when.Content); i += 2 {
+		key, value := when.Content[i], when.Content[i+1]
+
+		switch key.Value {
+		case "paths":
+			if err = value.Decode(&result.Paths); err != nil {
+				return
+			}
+
+		case "paths-ignore":
+			if err = value.Decode(&result.PathsIgnore); err != nil {
+				return
+			}
+
+		default:
+			content = append(content, key, value)
+		}
+	}
+	when.Content = content
+
+	return
+}
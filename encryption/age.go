@@ -0,0 +1,123 @@
+package encryption
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// Values encrypted using age are bound to a scope by prepending scope lines to the value, followed by an empty line:
+//
+//	repository: my-org/my-repo
+//	repository-id: 42
+//	branch: main
+//
+//	<secret value>
+//
+// Values that do not start with a scope line are not bound to a scope.
+var ageScopeKeys = map[string]bool{"repository": true, "repository-id": true, "branch": true}
+
+// IsAgeSecret reports whether the value is an ASCII armored age ciphertext.
+func IsAgeSecret(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), armor.Header)
+}
+
+// AddIdentity registers an X25519 age identity for decrypting values that were encrypted using public key encryption.
+// The first identity is the primary identity, whose recipient is published for encrypting new values.
+func (k *Keyring) AddIdentity(identity string) error {
+	parsed, err := age.ParseX25519Identity(identity)
+	if err != nil {
+		return fmt.Errorf("invalid age identity - %s", err)
+	}
+	k.identities = append(k.identities, parsed)
+	return nil
+}
+
+// Recipient returns the public key of the primary identity, which can be used for encrypting values locally using age.
+func (k *Keyring) Recipient() (string, error) {
+	if len(k.identities) == 0 {
+		return "", fmt.Errorf("no age identity configured")
+	}
+	return k.identities[0].Recipient().String(), nil
+}
+
+func (k *Keyring) decryptAge(value string) (string, error) {
+	if len(k.identities) == 0 {
+		return "", fmt.Errorf("no age identity configured")
+	}
+
+	identities := make([]age.Identity, len(k.identities))
+	for i, identity := range k.identities {
+		identities[i] = identity
+	}
+
+	reader, err := age.Decrypt(armor.NewReader(strings.NewReader(strings.TrimSpace(value))), identities...)
+	if err != nil {
+		return "", err
+	}
+	decrypted, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(decrypted), nil
+}
+
+// FormatAgeScope returns the scope lines to be prepended to a value before encrypting it using age, including the separating empty line.
+func FormatAgeScope(scope Scope) string {
+	var lines []string
+	if scope.Repository != "" {
+		lines = append(lines, "repository: "+scope.Repository)
+	}
+	if scope.RepositoryID != 0 {
+		lines = append(lines, "repository-id: "+strconv.FormatInt(scope.RepositoryID, 10))
+	}
+	if scope.Branch != "" {
+		lines = append(lines, "branch: "+scope.Branch)
+	}
+	return strings.Join(lines, "\n") + "\n\n"
+}
+
+// parseAgeScope splits the scope lines from a decrypted value.
+// Errors do not include the offending line, as it might be part of the secret value.
+func parseAgeScope(value string) (Scope, string, error) {
+	var scope Scope
+	rest := value
+	for i := 1; ; i++ {
+		line, remaining, found := strings.Cut(rest, "\n")
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" && i > 1 {
+			return scope, remaining, nil
+		}
+
+		key, field, ok := strings.Cut(line, ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || !ageScopeKeys[key] {
+			if i == 1 {
+				return Scope{}, value, nil
+			}
+			return Scope{}, "", fmt.Errorf("invalid scope line %v", i)
+		}
+		if !found {
+			return Scope{}, "", fmt.Errorf("missing empty line after scope")
+		}
+
+		field = strings.TrimSpace(field)
+		switch key {
+		case "repository":
+			scope.Repository = field
+		case "repository-id":
+			id, err := strconv.ParseInt(field, 10, 64)
+			if err != nil || id <= 0 {
+				return Scope{}, "", fmt.Errorf("invalid repository ID in scope line %v", i)
+			}
+			scope.RepositoryID = id
+		case "branch":
+			scope.Branch = field
+		}
+		rest = remaining
+	}
+}
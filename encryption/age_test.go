@@ -0,0 +1,76 @@
+package encryption
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+func encryptAge(t *testing.T, recipient *age.X25519Recipient, value string) string {
+	var buf bytes.Buffer
+	armorWriter := armor.NewWriter(&buf)
+	writer, err := age.Encrypt(armorWriter, recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(writer, value); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := armorWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestKeyringDecryptAgeScope(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := NewKeyring("default", "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.AddIdentity(identity.String()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		plaintext string
+		value     string
+		scope     Scope
+		fails     bool
+	}{
+		{"secret value", "secret value", Scope{}, false},
+		{"\nsecret value", "\nsecret value", Scope{}, false},
+		{"key: value\n\nsecret value", "key: value\n\nsecret value", Scope{}, false},
+		{FormatAgeScope(Scope{Repository: "org/repo", RepositoryID: 42, Branch: "main"}) + "secret\nvalue", "secret\nvalue", Scope{Repository: "org/repo", RepositoryID: 42, Branch: "main"}, false},
+		{"Repository: org/repo\r\n\r\nsecret value", "secret value", Scope{Repository: "org/repo"}, false},
+		{"branch: main\nsecret value", "", Scope{}, true},
+		{"branch: main", "", Scope{}, true},
+		{"repository-id: org/repo\n\nsecret value", "", Scope{}, true},
+	}
+
+	for _, test := range tests {
+		decrypted, header, err := keyring.Decrypt(encryptAge(t, identity.Recipient(), test.plaintext))
+		if test.fails {
+			if err == nil {
+				t.Errorf("%q: expected an error", test.plaintext)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: decrypting failed - %s", test.plaintext, err)
+			continue
+		}
+		if decrypted != test.value || header.Scope != test.scope {
+			t.Errorf("%q: expected %q with scope %+v, got %q with scope %+v", test.plaintext, test.value, test.scope, decrypted, header.Scope)
+		}
+	}
+}
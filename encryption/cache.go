@@ -0,0 +1,74 @@
+package encryption
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DERIVED_KEY_CACHE_SIZE is the number of derived keys a keyring remembers.
+const DERIVED_KEY_CACHE_SIZE = 1024
+
+// keyCache is a bounded LRU cache of derived keys, indexed by key ID and salt.
+// Only keys that successfully authenticated a ciphertext are added.
+type keyCache struct {
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+
+	sync.Mutex
+}
+
+type keyCacheEntry struct {
+	id  string
+	key []byte
+}
+
+func newKeyCache(size int) *keyCache {
+	return &keyCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func keyCacheID(keyID string, salt []byte) string {
+	return keyID + "\x00" + string(salt)
+}
+
+func (c *keyCache) Get(keyID string, salt []byte) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	element, ok := c.entries[keyCacheID(keyID, salt)]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*keyCacheEntry).key, true
+}
+
+func (c *keyCache) Add(keyID string, salt []byte, key []byte) {
+	if c == nil || c.size <= 0 {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	id := keyCacheID(keyID, salt)
+	if element, ok := c.entries[id]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[id] = c.order.PushFront(&keyCacheEntry{id: id, key: key})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*keyCacheEntry).id)
+	}
+}
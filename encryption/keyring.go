@@ -0,0 +1,102 @@
+package encryption
+
+import (
+	"fmt"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Keyring holds the keys for encrypting and decrypting secrets.
+// Values are always encrypted using the primary key, while any key of the keyring may be used for decryption.
+// Values that were encrypted using age are decrypted using the keyring's identities.
+type Keyring struct {
+	PrimaryID string
+
+	keys       map[string]string
+	ids        []string
+	identities []*age.X25519Identity
+	cache      *keyCache
+}
+
+func NewKeyring(primaryID, primaryKey string) (*Keyring, error) {
+	k := &Keyring{PrimaryID: primaryID, keys: make(map[string]string), cache: newKeyCache(DERIVED_KEY_CACHE_SIZE)}
+	if err := k.Add(primaryID, primaryKey); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Add registers an additional decryption key.
+func (k *Keyring) Add(id, key string) error {
+	if id == "" || len(id) > 255 || strings.ContainsAny(id, ": \t\r\n") {
+		return fmt.Errorf("invalid key ID \"%s\"", id)
+	}
+	if key == "" {
+		return fmt.Errorf("empty key for key ID %s", id)
+	}
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("duplicate key ID %s", id)
+	}
+	k.keys[id] = key
+	k.ids = append(k.ids, id)
+	return nil
+}
+
+func (k *Keyring) Encrypt(value string, scope Scope) (string, error) {
+	return EncryptSecret(k.PrimaryID, k.keys[k.PrimaryID], value, scope)
+}
+
+// Decrypt decrypts the value using the key referenced by the value and returns its verified header.
+// The header of values encrypted using age only contains their scope.
+// Values that do not reference a key are tried with every key of the keyring, starting with the primary key.
+func (k *Keyring) Decrypt(value string) (string, Header, error) {
+	if IsAgeSecret(value) {
+		decrypted, err := k.decryptAge(value)
+		if err != nil {
+			return "", Header{}, err
+		}
+		scope, decrypted, err := parseAgeScope(decrypted)
+		if err != nil {
+			return "", Header{}, err
+		}
+		return decrypted, Header{Scope: scope}, nil
+	}
+
+	// values that cannot be parsed are still tried with every key, as legacy ciphertexts have no header
+	parsed, _ := ParseHeader(value)
+	keyID := parsed.KeyID
+
+	// a value referencing a key is only decrypted using that key, every other key is only tried for legacy decryption,
+	// since legacy ciphertexts may look like they reference a key
+	var decrypted string
+	var header Header
+	var err error
+	key, known := k.keys[keyID]
+	if known {
+		if decrypted, header, err = decryptSecret(keyID, key, value, k.cache); err == nil {
+			return decrypted, header, nil
+		}
+	}
+	for _, id := range k.ids {
+		if id == keyID {
+			continue
+		}
+		if decrypted, header, err = decryptSecret(id, k.keys[id], value, k.cache); err == nil {
+			return decrypted, header, nil
+		}
+	}
+	if keyID != "" && !known {
+		return "", Header{}, fmt.Errorf("unknown key ID %s", keyID)
+	}
+	return "", Header{}, err
+}
+
+// Reencrypt decrypts the value and encrypts it again using the primary key, keeping its scope.
+func (k *Keyring) Reencrypt(value string) (string, error) {
+	decrypted, header, err := k.Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+	return k.Encrypt(decrypted, header.Scope)
+}
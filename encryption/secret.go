@@ -7,61 +7,239 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
+	"fmt"
 	"io"
+	"strconv"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Ciphertexts are encoded as `header | salt | nonce | sealed data`, where the header is authenticated as additional data.
+//
+//   - version 1: `1 | key ID length | key ID`
+//   - version 2: `2 | key ID length | key ID | repository length | repository | repository ID length | repository ID | branch length | branch`
+//
+// Values with an empty key ID may have been encrypted using any key.
+// Values without a known version prefix are treated as legacy ciphertexts, which use an unsalted MD5 hash of the passphrase as key.
+const (
+	VERSION_ARGON2ID        byte = 1
+	VERSION_ARGON2ID_SCOPED byte = 2
+
+	saltSize = 16
+	keySize  = 32
+
+	argon2Time    = 1
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
 )
 
-func EncryptSecret(key, value string) (string, error) {
-	encrypted, err := encrypt([]byte(value), key)
+// Scope restricts where an encrypted value may be used.
+// An empty repository means that the value may be used in any repository, an empty branch pattern means that the value may be used for any ref.
+// If the repository ID is set, the value is bound to the repository itself rather than to its name, so that it survives renames
+// and cannot be used by another repository created under the same name.
+type Scope struct {
+	Repository   string
+	RepositoryID int64
+	Branch       string
+}
+
+func (s Scope) IsZero() bool {
+	return s.Repository == "" && s.RepositoryID == 0 && s.Branch == ""
+}
+
+// Header contains the unencrypted metadata of a ciphertext.
+type Header struct {
+	Version byte
+	KeyID   string
+	Scope   Scope
+}
+
+func EncryptSecret(keyID, key, value string, scope Scope) (string, error) {
+	encrypted, err := encrypt([]byte(value), keyID, key, scope)
 	if err != nil {
 		return "", err
 	}
 	return base64.URLEncoding.EncodeToString(encrypted), nil
 }
 
-func DecryptSecret(key, value string) (string, error) {
+// ParseHeader returns the metadata of an encrypted value.
+// Legacy values result in an empty header. Note that the header is not verified until the value is decrypted.
+func ParseHeader(value string) (Header, error) {
 	raw, err := base64.URLEncoding.DecodeString(value)
 	if err != nil {
-		return "", err
+		return Header{}, err
 	}
-	decrypted, err := decrypt(raw, key)
-	return string(decrypted), err
+	header, _, _, err := splitHeader(raw)
+	return header, err
 }
 
-func createHash(key string) string {
-	hasher := md5.New()
-	hasher.Write([]byte(key))
-	return hex.EncodeToString(hasher.Sum(nil))
+// DecryptSecret decrypts the value using the key with the given ID and returns its verified header.
+// Versioned values are only decrypted if they reference the key ID or no key at all, otherwise the value is decrypted as a legacy value.
+func DecryptSecret(keyID, key, value string) (string, Header, error) {
+	return decryptSecret(keyID, key, value, nil)
 }
 
-func encrypt(data []byte, passphrase string) ([]byte, error) {
-	block, _ := aes.NewCipher([]byte(createHash(passphrase)))
-	gcm, err := cipher.NewGCM(block)
+func decryptSecret(keyID, key, value string, cache *keyCache) (string, Header, error) {
+	raw, err := base64.URLEncoding.DecodeString(value)
 	if err != nil {
-		return nil, err
+		return "", Header{}, err
 	}
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
-		panic(err.Error())
+
+	if header, additionalData, data, err := splitHeader(raw); err == nil && header.Version != 0 && (header.KeyID == "" || header.KeyID == keyID) {
+		if decrypted, err := decrypt(additionalData, data, keyID, key, cache); err == nil {
+			return string(decrypted), header, nil
+		}
+		// legacy ciphertexts start with a random nonce, which may coincide with a version header
+	}
+
+	decrypted, err := decryptLegacy(raw, key)
+	return string(decrypted), Header{}, err
+}
+
+// splitHeader splits a ciphertext into its header, the additional data to authenticate and the remaining data.
+func splitHeader(data []byte) (header Header, additionalData, rest []byte, err error) {
+	if len(data) == 0 {
+		return Header{}, nil, nil, fmt.Errorf("ciphertext too short")
+	}
+
+	var fields int
+	switch data[0] {
+	case VERSION_ARGON2ID:
+		fields = 1
+	case VERSION_ARGON2ID_SCOPED:
+		fields = 4
+	default:
+		return Header{}, nil, data, nil
+	}
+
+	values := make([]string, fields)
+	offset := 1
+	for i := range values {
+		if len(data) < offset+1 || len(data) < offset+1+int(data[offset]) {
+			return Header{}, nil, nil, fmt.Errorf("ciphertext too short")
+		}
+		length := int(data[offset])
+		values[i] = string(data[offset+1 : offset+1+length])
+		offset += 1 + length
 	}
-	ciphertext := gcm.Seal(nonce, nonce, data, nil)
-	return ciphertext, nil
+
+	header = Header{Version: data[0], KeyID: values[0]}
+	if fields == 4 {
+		header.Scope = Scope{Repository: values[1], Branch: values[3]}
+		if values[2] != "" {
+			if header.Scope.RepositoryID, err = strconv.ParseInt(values[2], 10, 64); err != nil || header.Scope.RepositoryID <= 0 {
+				return Header{}, nil, nil, fmt.Errorf("invalid repository ID")
+			}
+		}
+	}
+	return header, data[:offset], data[offset:], nil
 }
 
-func decrypt(data []byte, passphrase string) ([]byte, error) {
-	key := []byte(createHash(passphrase))
+// deriveKey derives an AES-256 key from the passphrase using Argon2id.
+func deriveKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, argon2Time, argon2Memory, argon2Threads, keySize)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
-	gcm, err := cipher.NewGCM(block)
+	return cipher.NewGCM(block)
+}
+
+func encrypt(data []byte, keyID, passphrase string, scope Scope) ([]byte, error) {
+	fields := []string{keyID}
+	version := VERSION_ARGON2ID
+	if !scope.IsZero() {
+		var repositoryID string
+		if scope.RepositoryID != 0 {
+			repositoryID = strconv.FormatInt(scope.RepositoryID, 10)
+		}
+		fields = append(fields, scope.Repository, repositoryID, scope.Branch)
+		version = VERSION_ARGON2ID_SCOPED
+	}
+
+	header := []byte{version}
+	for _, field := range fields {
+		if len(field) > 255 {
+			return nil, fmt.Errorf("header field \"%s\" too long", field)
+		}
+		header = append(header, byte(len(field)))
+		header = append(header, field...)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(deriveKey(passphrase, salt))
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	result := make([]byte, 0, len(header)+saltSize+len(nonce)+len(data)+gcm.Overhead())
+	result = append(result, header...)
+	result = append(result, salt...)
+	result = append(result, nonce...)
+	return gcm.Seal(result, nonce, data, header), nil
+}
+
+// decrypt opens a versioned ciphertext. Derived keys are cached once they successfully authenticated the ciphertext,
+// as every value carries its own salt and Argon2id is intentionally expensive.
+func decrypt(additionalData, data []byte, keyID, passphrase string, cache *keyCache) ([]byte, error) {
+	if len(data) < saltSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	salt, data := data[:saltSize], data[saltSize:]
+
+	key, cached := cache.Get(keyID, salt)
+	if !cached {
+		key = deriveKey(passphrase, salt)
+	}
+
+	gcm, err := newGCM(key)
 	if err != nil {
 		return nil, err
 	}
+
 	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
 		return nil, err
 	}
+
+	if !cached {
+		cache.Add(keyID, salt, key)
+	}
 	return plaintext, nil
 }
+
+func createHash(key string) string {
+	hasher := md5.New()
+	hasher.Write([]byte(key))
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+func decryptLegacy(data []byte, passphrase string) ([]byte, error) {
+	gcm, err := newGCM([]byte(createHash(passphrase)))
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,162 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"testing"
+)
+
+func TestEncryptSecretRoundTrip(t *testing.T) {
+	for _, scope := range []Scope{{}, {Repository: "org/repo", Branch: "main"}, {Repository: "org/repo", RepositoryID: 42}} {
+		encrypted, err := EncryptSecret("default", "passphrase", "secret value", scope)
+		if err != nil {
+			t.Fatalf("encrypting failed - %s", err)
+		}
+
+		decrypted, header, err := DecryptSecret("default", "passphrase", encrypted)
+		if err != nil {
+			t.Fatalf("decrypting failed - %s", err)
+		}
+		if decrypted != "secret value" {
+			t.Errorf("expected %q, got %q", "secret value", decrypted)
+		}
+		if header.KeyID != "default" || header.Scope != scope {
+			t.Errorf("unexpected header %+v", header)
+		}
+
+		if _, _, err := DecryptSecret("default", "wrong passphrase", encrypted); err == nil {
+			t.Errorf("decrypting with a wrong passphrase succeeded")
+		}
+	}
+}
+
+func TestDecryptSecretRejectsModifiedHeader(t *testing.T) {
+	encrypted, err := EncryptSecret("default", "passphrase", "secret value", Scope{Repository: "org/repo"})
+	if err != nil {
+		t.Fatalf("encrypting failed - %s", err)
+	}
+
+	raw, _ := base64.URLEncoding.DecodeString(encrypted)
+	header, _, rest, err := splitHeader(raw)
+	if err != nil {
+		t.Fatalf("splitting header failed - %s", err)
+	}
+
+	// removing the scope must not result in a valid unscoped value
+	unscoped := append([]byte{VERSION_ARGON2ID, byte(len(header.KeyID))}, header.KeyID...)
+	unscoped = append(unscoped, rest...)
+	if _, _, err := DecryptSecret("default", "passphrase", base64.URLEncoding.EncodeToString(unscoped)); err == nil {
+		t.Errorf("decrypting a value with modified header succeeded")
+	}
+}
+
+func TestDecryptSecretLegacy(t *testing.T) {
+	gcm, err := newGCM([]byte(createHash("passphrase")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		t.Fatal(err)
+	}
+	legacy := base64.URLEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte("secret value"), nil))
+
+	decrypted, header, err := DecryptSecret("default", "passphrase", legacy)
+	if err != nil {
+		t.Fatalf("decrypting failed - %s", err)
+	}
+	if decrypted != "secret value" {
+		t.Errorf("expected %q, got %q", "secret value", decrypted)
+	}
+	if header != (Header{}) {
+		t.Errorf("expected empty header, got %+v", header)
+	}
+
+	keyring, err := NewKeyring("current", "new passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.Add("previous", "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if decrypted, _, err := keyring.Decrypt(legacy); err != nil || decrypted != "secret value" {
+		t.Errorf("decrypting using keyring failed - %q, %v", decrypted, err)
+	}
+}
+
+func TestKeyringReencrypt(t *testing.T) {
+	previous, err := NewKeyring("previous", "old passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := Scope{Repository: "org/repo", Branch: "release/*"}
+	encrypted, err := previous.Encrypt("secret value", scope)
+	if err != nil {
+		t.Fatalf("encrypting failed - %s", err)
+	}
+
+	keyring, err := NewKeyring("current", "new passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.Add("previous", "old passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	reencrypted, err := keyring.Reencrypt(encrypted)
+	if err != nil {
+		t.Fatalf("re-encrypting failed - %s", err)
+	}
+
+	// the second decryption uses the cached key
+	for i := 0; i < 2; i++ {
+		decrypted, header, err := keyring.Decrypt(reencrypted)
+		if err != nil {
+			t.Fatalf("decrypting failed - %s", err)
+		}
+		if decrypted != "secret value" || header.KeyID != "current" || header.Scope != scope {
+			t.Errorf("unexpected result %q, %+v", decrypted, header)
+		}
+	}
+
+	if _, _, err := previous.Decrypt(reencrypted); err == nil {
+		t.Errorf("decrypting with unknown key ID succeeded")
+	}
+}
+
+func TestKeyCacheEviction(t *testing.T) {
+	cache := newKeyCache(2)
+	cache.Add("a", []byte("salt"), []byte("1"))
+	cache.Add("b", []byte("salt"), []byte("2"))
+	cache.Get("a", []byte("salt"))
+	cache.Add("c", []byte("salt"), []byte("3"))
+
+	if _, ok := cache.Get("b", []byte("salt")); ok {
+		t.Errorf("expected least recently used key to be evicted")
+	}
+	for _, id := range []string{"a", "c"} {
+		if _, ok := cache.Get(id, []byte("salt")); !ok {
+			t.Errorf("expected key %s to be cached", id)
+		}
+	}
+}
+
+func TestKeyringDecryptWithoutKeyID(t *testing.T) {
+	encrypted, err := EncryptSecret("", "old passphrase", "secret value", Scope{})
+	if err != nil {
+		t.Fatalf("encrypting failed - %s", err)
+	}
+
+	keyring, err := NewKeyring("current", "new passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.Add("previous", "old passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	if decrypted, _, err := keyring.Decrypt(encrypted); err != nil || decrypted != "secret value" {
+		t.Errorf("decrypting using keyring failed - %q, %v", decrypted, err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+func NewRepositoryFilter(settings map[string]string) (*RepositoryFilter, error) {
+	f := &RepositoryFilter{
+		Include:        strings.Fields(settings["INCLUDE_REPOSITORIES"]),
+		Exclude:        strings.Fields(settings["EXCLUDE_REPOSITORIES"]),
+		RequiredTopics: strings.Fields(strings.ToLower(settings["REQUIRED_TOPICS"])),
+	}
+
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid repository pattern \"%s\" - %s", pattern, err)
+		}
+	}
+
+	var err error
+	if f.SkipArchived, err = boolSetting(settings, "SKIP_ARCHIVED"); err != nil {
+		return nil, err
+	}
+	if f.SkipForks, err = boolSetting(settings, "SKIP_FORKS"); err != nil {
+		return nil, err
+	}
+	if f.SkipMirrors, err = boolSetting(settings, "SKIP_MIRRORS"); err != nil {
+		return nil, err
+	}
+	if f.SkipTemplates, err = boolSetting(settings, "SKIP_TEMPLATES"); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// RepositoryFilter limits the set of repositories the plugin operates on.
+type RepositoryFilter struct {
+	Include, Exclude []string
+	RequiredTopics   []string
+
+	SkipArchived, SkipForks, SkipMirrors, SkipTemplates bool
+}
+
+// MatchName reports whether a repository's full name is accepted by the include and exclude patterns.
+func (f *RepositoryFilter) MatchName(repository string) bool {
+	repository = strings.ToLower(repository)
+
+	if len(f.Include) > 0 {
+		var found bool
+		for _, pattern := range f.Include {
+			if ok, _ := path.Match(strings.ToLower(pattern), repository); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, pattern := range f.Exclude {
+		if ok, _ := path.Match(strings.ToLower(pattern), repository); ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NeedsDetails reports whether the filter needs more than a repository's name to make a decision.
+func (f *RepositoryFilter) NeedsDetails() bool {
+	return len(f.RequiredTopics) > 0 || f.SkipArchived || f.SkipForks || f.SkipMirrors || f.SkipTemplates
+}
+
+// Match reports whether a repository is accepted by the filter.
+func (f *RepositoryFilter) Match(repo RepositoryResponse) bool {
+	if !f.MatchName(repo.FullName) {
+		return false
+	}
+
+	if (f.SkipArchived && repo.Archived) ||
+		(f.SkipForks && repo.Fork) ||
+		(f.SkipMirrors && repo.Mirror) ||
+		(f.SkipTemplates && repo.Template) {
+		return false
+	}
+
+	if len(f.RequiredTopics) > 0 {
+		topics := make(map[string]bool, len(repo.Topics))
+		for _, topic := range repo.Topics {
+			topics[strings.ToLower(topic)] = true
+		}
+		for _, topic := range f.RequiredTopics {
+			if !topics[topic] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Filter returns all repositories accepted by the filter.
+func (f *RepositoryFilter) Filter(repos SearchResult) SearchResult {
+	result := make(SearchResult, 0, len(repos))
+	for _, repo := range repos {
+		if f.Match(repo) {
+			result = append(result, repo)
+		}
+	}
+	return result
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -43,6 +46,52 @@ func (p *GiteaPlugin) FetchRepoFileContent(repository string, file string, ref s
 	return resp, nil
 }
 
+// Send a request to the Gitea API.
+// The path is relative to the API root, e.g. "repos/owner/name".
+// If body is not nil, it is sent JSON encoded. If result is not nil and the request succeeded, the response is decoded into result.
+// The returned status code is 0 if no response was received.
+// If the server responds with a non-2xx status, the status and an error containing the response body are returned.
+func (p *GiteaPlugin) RequestAPI(method, path string, body, result any) (int, error) {
+	var content io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		content = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%sapi/v1/%s", p.InternalUrl, path), content)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.Token))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("status %v - %s", resp.StatusCode, string(data))
+	}
+
+	if result != nil {
+		err = json.NewDecoder(resp.Body).Decode(result)
+		if err != nil {
+			return resp.StatusCode, err
+		}
+	}
+
+	return resp.StatusCode, nil
+}
+
 var ReeveFileExtensions = []string{".yaml", ".yml", ".yaml.tmpl", ".yml.tmpl"}
 
 func IsTemplate(file string) bool {
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// GlobToRegexp converts a glob pattern with doublestar semantics to an anchored regular expression.
+//
+//   - `*` matches any sequence of characters except `/`
+//   - `**` matches any sequence of characters including `/`, as a path segment it also matches zero directories
+//   - `?` matches a single character except `/`
+//   - `[abc]`, `[a-z]` and `[!abc]` match a single character from (or not from) a character class
+//   - `{a,b}` matches any of the comma separated alternatives
+func GlobToRegexp(pattern string) (string, error) {
+	var result strings.Builder
+	result.WriteString("^")
+
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				segmentStart := i == 0 || pattern[i-1] == '/'
+				segmentEnd := i+2 == len(pattern) || pattern[i+2] == '/'
+
+				switch {
+				case segmentStart && i+2 < len(pattern) && pattern[i+2] == '/':
+					// `**/` matches zero or more directories
+					result.WriteString("(?:.*/)?")
+					i += 2
+				case segmentStart && segmentEnd && i > 0:
+					// trailing `/**` matches the directory itself and all of its contents
+					current := result.String()
+					result.Reset()
+					result.WriteString(strings.TrimSuffix(current, "/"))
+					result.WriteString("(?:/.*)?")
+					i++
+				default:
+					result.WriteString(".*")
+					i++
+				}
+			} else {
+				result.WriteString("[^/]*")
+			}
+
+		case '?':
+			result.WriteString("[^/]")
+
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("invalid glob pattern \"%s\" - unterminated character class", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			result.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+
+		case '{':
+			depth++
+			result.WriteString("(?:")
+
+		case '}':
+			if depth == 0 {
+				return "", fmt.Errorf("invalid glob pattern \"%s\" - unexpected }", pattern)
+			}
+			depth--
+			result.WriteString(")")
+
+		case ',':
+			if depth > 0 {
+				result.WriteString("|")
+			} else {
+				result.WriteString(",")
+			}
+
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				result.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			} else {
+				result.WriteString(regexp.QuoteMeta(`\`))
+			}
+
+		default:
+			result.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	if depth > 0 {
+		return "", fmt.Errorf("invalid glob pattern \"%s\" - unterminated alternatives", pattern)
+	}
+
+	result.WriteString("$")
+
+	if _, err := regexp.Compile(result.String()); err != nil {
+		return "", fmt.Errorf("invalid glob pattern \"%s\" - %s", pattern, err)
+	}
+
+	return result.String(), nil
+}
+
+// CompileGlobs compiles a list of glob patterns.
+func CompileGlobs(patterns []string) ([]*regexp.Regexp, error) {
+	result := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		expression, err := GlobToRegexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = regexp.MustCompile(expression)
+	}
+	return result, nil
+}
+
+// MatchAny reports whether name matches any of the compiled patterns.
+func MatchAny(patterns []*regexp.Regexp, name string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		matches []string
+		rejects []string
+	}{
+		{"*.go", []string{"main.go", ".go"}, []string{"cmd/main.go", "main.golang"}},
+		{"src/?.go", []string{"src/a.go"}, []string{"src/ab.go", "src//.go"}},
+		{"**/*.go", []string{"main.go", "cmd/main.go", "a/b/c/main.go"}, []string{"main.txt", "cmd/main.go/x"}},
+		{"docs/**/index.md", []string{"docs/index.md", "docs/a/index.md", "docs/a/b/index.md"}, []string{"docs/aindex.md", "index.md"}},
+		{"docs/**", []string{"docs", "docs/index.md", "docs/a/b"}, []string{"docsx", "other/docs/a"}},
+		{"foo**", []string{"foo", "foobar", "foo/bar", "foobar/baz"}, []string{"fo", "xfoo"}},
+		{"foo/**bar", []string{"foo/bar", "foo/xbar", "foo/x/bar"}, []string{"foo/baz"}},
+		{"**", []string{"", "a", "a/b"}, nil},
+		{"release/[0-9].x", []string{"release/1.x"}, []string{"release/a.x", "release/12.x"}},
+		{"[!.]*", []string{"main.go"}, []string{".gitignore"}},
+		{"*.{go,mod}", []string{"main.go", "go.mod"}, []string{"go.sum", "main.{go,mod}"}},
+		{"{cmd,pkg}/**", []string{"cmd", "cmd/a/b", "pkg/a"}, []string{"internal/a"}},
+		{"a,b", []string{"a,b"}, []string{"a", "b"}},
+		{`\*.go`, []string{"*.go"}, []string{"main.go"}},
+		{"v1.0", []string{"v1.0"}, []string{"v1x0"}},
+	}
+
+	for _, test := range tests {
+		expression, err := GlobToRegexp(test.pattern)
+		if err != nil {
+			t.Errorf("%s: unexpected error - %s", test.pattern, err)
+			continue
+		}
+		compiled := regexp.MustCompile(expression)
+		for _, name := range test.matches {
+			if !compiled.MatchString(name) {
+				t.Errorf("%s (%s): expected %q to match", test.pattern, expression, name)
+			}
+		}
+		for _, name := range test.rejects {
+			if compiled.MatchString(name) {
+				t.Errorf("%s (%s): expected %q not to match", test.pattern, expression, name)
+			}
+		}
+	}
+}
+
+func TestGlobToRegexpInvalid(t *testing.T) {
+	for _, pattern := range []string{"[abc", "{a,b", "a}", "[z-a]"} {
+		if _, err := GlobToRegexp(pattern); err == nil {
+			t.Errorf("%s: expected an error", pattern)
+		}
+	}
+}
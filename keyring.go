@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/reeveci/plugin-gitea/encryption"
+)
+
+func NewKeyring(settings map[string]string) (*encryption.Keyring, error) {
+	primaryKey, err := requireSetting(settings, "SECRET_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	keyring, err := encryption.NewKeyring(defaultSetting(settings, "SECRET_KEY_ID", "default"), primaryKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid setting SECRET_KEY_ID - %s", err)
+	}
+
+	// entries are separated by newlines, so that passphrases may contain any other character
+	var index int
+	for _, entry := range strings.Split(settings["SECRET_KEYS"], "\n") {
+		entry = strings.TrimSuffix(entry, "\r")
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		index++
+
+		// entries are not included in errors, as they contain the passphrase
+		id, key, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid setting SECRET_KEYS - entry %v should have the form id:key", index)
+		}
+		if err := keyring.Add(strings.TrimSpace(id), key); err != nil {
+			return nil, fmt.Errorf("invalid setting SECRET_KEYS - entry %v - %s", index, err)
+		}
+	}
+
+	for _, identity := range strings.Fields(settings["SECRET_AGE_IDENTITIES"]) {
+		if err := keyring.AddIdentity(identity); err != nil {
+			return nil, fmt.Errorf("invalid setting SECRET_AGE_IDENTITIES - %s", err)
+		}
+	}
+
+	return keyring, nil
+}
+
+// checkSecretScope reports an error if the scope of an encrypted value does not permit its usage for the given repository and ref.
+// Scopes containing a repository ID are matched by ID, otherwise the repository name is compared.
+func checkSecretScope(scope encryption.Scope, repositoryID int64, repository, ref string) error {
+	if scope.RepositoryID != 0 {
+		if scope.RepositoryID != repositoryID {
+			return fmt.Errorf("secret is restricted to repository %s (ID %v)", scope.Repository, scope.RepositoryID)
+		}
+	} else if scope.Repository != "" && !strings.EqualFold(scope.Repository, repository) {
+		return fmt.Errorf("secret is restricted to repository %s", scope.Repository)
+	}
+
+	if scope.Branch != "" {
+		patterns, err := CompileGlobs([]string{scope.Branch})
+		if err != nil {
+			return fmt.Errorf("invalid branch pattern \"%s\" - %s", scope.Branch, err)
+		}
+		if !strings.HasPrefix(ref, "refs/heads/") || !MatchAny(patterns, strings.TrimPrefix(ref, "refs/heads/")) {
+			return fmt.Errorf("secret is restricted to branches matching %s", scope.Branch)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/reeveci/reeve-lib/schema"
+)
+
+// credentialPatterns are well-known credential formats.
+// Patterns that also match other values, like Gitea tokens matching commit IDs, are only applied if the name suggests a credential.
+var credentialPatterns = []struct {
+	Name         string
+	Pattern      *regexp.Regexp
+	RequiresName bool
+}{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY( BLOCK)?-----`), false},
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`), false},
+	{"Gitea token", regexp.MustCompile(`^[0-9a-f]{40}$`), true},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), false},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`), false},
+	{"JSON web token", regexp.MustCompile(`^eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*$`), false},
+}
+
+// credentialNamePattern matches variable and param names that suggest a credential.
+var credentialNamePattern = regexp.MustCompile(`(?i)TOKEN|PASSWORD|KEY|SECRET`)
+
+// tokenPattern matches values consisting of characters commonly used in generated tokens.
+var tokenPattern = regexp.MustCompile(`^[A-Za-z0-9+/=_-]+$`)
+
+// tokenCharacterClasses must all be present in a value for it to be considered a generated token.
+var tokenCharacterClasses = []*regexp.Regexp{regexp.MustCompile(`[a-z]`), regexp.MustCompile(`[A-Z]`), regexp.MustCompile(`[0-9]`)}
+
+const (
+	minEntropyLength = 32
+	minEntropy       = 4.3
+)
+
+// DetectCredential reports whether the value of a variable or param looks like a credential, either because it matches a well-known credential format
+// or because it is a long token-like word with high entropy. The result describes the kind of credential.
+func DetectCredential(name, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	credentialName := credentialNamePattern.MatchString(name)
+
+	for _, credential := range credentialPatterns {
+		if credential.RequiresName && !credentialName {
+			continue
+		}
+		if credential.Pattern.MatchString(value) {
+			return credential.Name, true
+		}
+	}
+
+	if len(value) >= minEntropyLength && isToken(value) && shannonEntropy(value) >= minEntropy {
+		return "high entropy value", true
+	}
+
+	return "", false
+}
+
+func isToken(value string) bool {
+	if !tokenPattern.MatchString(value) {
+		return false
+	}
+	for _, class := range tokenCharacterClasses {
+		if !class.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// shannonEntropy calculates the entropy of a string in bits per character.
+func shannonEntropy(value string) float64 {
+	counts := make(map[rune]int)
+	var total int
+	for _, c := range value {
+		counts[c]++
+		total++
+	}
+
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// LintFinding is a value that looks like a plaintext credential.
+type LintFinding struct {
+	Location string
+	Kind     string
+}
+
+// LintPipelineParams finds literal step params that look like credentials.
+// Locations have the form `step <step> param <param>`.
+func LintPipelineParams(pipeline *schema.PipelineDefinition) []LintFinding {
+	var result []LintFinding
+	for i, step := range pipeline.Steps {
+		stepName := step.Name
+		if stepName == "" {
+			stepName = fmt.Sprintf("#%v", i+1)
+		}
+
+		keys := make([]string, 0, len(step.Params))
+		for key := range step.Params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			var value string
+			switch param := step.Params[key].(type) {
+			case string:
+				value = param
+			case schema.LiteralParam:
+				value = string(param)
+			default:
+				continue
+			}
+
+			if kind, ok := DetectCredential(key, value); ok {
+				result = append(result, LintFinding{Location: fmt.Sprintf("step %s param %s", stepName, key), Kind: kind})
+			}
+		}
+	}
+	return result
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/reeveci/plugin-gitea/encryption"
 	"github.com/reeveci/reeve-lib/plugin"
 	"github.com/reeveci/reeve-lib/schema"
 )
@@ -33,8 +34,13 @@ type GiteaPlugin struct {
 	TaskDomains                      map[string]string
 	TrustedDomains, TrustedTasks     []string
 	SetupTask                        string
-	SecretKey                        string
+	Keyring                          *encryption.Keyring
+	WithholdUnverifiedSecrets        bool
+	RejectPlaintextSecrets           bool
+	TolerateSecretErrors             bool
 	DiscoverySchedule                string
+	Filter                           *RepositoryFilter
+	Deliveries                       *WebhookHistory
 
 	Log hclog.Logger
 	API plugin.ReeveAPI
@@ -95,10 +101,27 @@ func (p *GiteaPlugin) Register(settings map[string]string, api plugin.ReeveAPI)
 	if p.SetupTask, err = requireSetting(settings, "SETUP_GIT_TASK"); err != nil {
 		return
 	}
-	if p.SecretKey, err = requireSetting(settings, "SECRET_KEY"); err != nil {
+	if p.Keyring, err = NewKeyring(settings); err != nil {
+		return
+	}
+	if p.WithholdUnverifiedSecrets, err = boolSetting(settings, "WITHHOLD_UNVERIFIED_SECRETS"); err != nil {
+		return
+	}
+	if p.RejectPlaintextSecrets, err = boolSetting(settings, "REJECT_PLAINTEXT_SECRETS"); err != nil {
+		return
+	}
+	if p.TolerateSecretErrors, err = boolSetting(settings, "TOLERATE_SECRET_ERRORS"); err != nil {
 		return
 	}
 	p.DiscoverySchedule = defaultSetting(settings, "DISCOVERY_SCHEDULE", "0 12 * * *")
+	if p.Filter, err = NewRepositoryFilter(settings); err != nil {
+		return
+	}
+	var historySize int
+	if historySize, err = intSetting(settings, "WEBHOOK_HISTORY", 100); err != nil {
+		return
+	}
+	p.Deliveries = NewWebhookHistory(historySize)
 
 	if p.Scanner, err = NewScanner(p); err != nil {
 		return
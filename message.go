@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -47,36 +46,15 @@ func (p *GiteaPlugin) Message(source string, message schema.Message) error {
 
 	switch message.Options["type"] {
 	case "webhook":
-		var webhook Webhook
-		err := json.Unmarshal(message.Data, &webhook)
-		if err != nil {
-			return fmt.Errorf("error parsing webhook message %s", message.Data)
-		}
-
-		p.Scanner.Notify(webhook.Repository.FullName)
-
-		commitMessage := strings.ToLower(webhook.HeadCommit.Message)
-		if strings.Contains(commitMessage, "[skip ci]") || strings.Contains(commitMessage, "[ci skip]") {
-			return nil
-		}
+		return p.HandleWebhook(message)
 
-		trigger := map[string]string{
-			"type":          "git",
-			"trigger":       "push",
-			"ref":           webhook.Ref,
-			"commit":        webhook.HeadCommit.ID,
-			"commitMessage": webhook.HeadCommit.Message,
-			"repository":    webhook.Repository.FullName,
-			"repositoryURL": webhook.Repository.HtmlURL,
-			"cloneURL":      webhook.Repository.CloneURL,
-			"defaultBranch": webhook.Repository.DefaultBranch,
-			"files":         strings.Join(collectFiles(webhook), "\n"),
+	case "replay":
+		delivery := message.Options["delivery"]
+		if delivery == "" {
+			return fmt.Errorf("missing delivery")
 		}
 
-		err = p.API.NotifyTriggers([]schema.Trigger{trigger})
-		if err != nil {
-			return fmt.Errorf("error notifying trigger - %s", err)
-		}
+		return p.ReplayWebhook(delivery)
 
 	case "operation":
 		operation := message.Options["operation"]
@@ -110,18 +88,12 @@ func (p *GiteaPlugin) Message(source string, message schema.Message) error {
 			}
 
 			if commitResponse != nil {
-				triggers = append(triggers, map[string]string{
-					"type":          "git",
-					"trigger":       "action",
-					"action":        action,
-					"ref":           fmt.Sprintf("refs/heads/%s", repo.DefaultBranch),
-					"commit":        commitResponse.Commit.ID,
-					"commitMessage": commitResponse.Commit.Message,
-					"repository":    repo.FullName,
-					"repositoryURL": repo.HtmlURL,
-					"cloneURL":      repo.CloneURL,
-					"defaultBranch": repo.DefaultBranch,
-				})
+				trigger := NewActionTrigger(action, repo, fmt.Sprintf("refs/heads/%s", repo.DefaultBranch), commitResponse.Commit.ID, commitResponse.Commit.Message)
+				AddCommitDetails(trigger, commitResponse.Commit)
+				if files, ok := p.collectActionFiles(repo.FullName, message.Options["base"], commitResponse.Commit.ID); ok {
+					trigger["files"] = strings.Join(files, "\n")
+				}
+				triggers = append(triggers, trigger)
 			}
 		}
 
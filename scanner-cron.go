@@ -1,18 +1,20 @@
 package main
 
-func NewCronScanner(plugin *GiteaPlugin, repository string) DocumentScanner {
+func NewCronScanner(plugin *GiteaPlugin, repositoryID int64, repository string) DocumentScanner {
 	return &CronScanner{
-		plugin:     plugin,
-		repository: repository,
-		rules:      NewCronRuleset(),
+		plugin:       plugin,
+		repositoryID: repositoryID,
+		repository:   repository,
+		rules:        NewCronRuleset(),
 	}
 }
 
 type CronScanner struct {
-	plugin     *GiteaPlugin
-	repository string
-	rules      CronRuleset
-	done       bool
+	plugin       *GiteaPlugin
+	repositoryID int64
+	repository   string
+	rules        CronRuleset
+	done         bool
 }
 
 func (s *CronScanner) Init(rootFiles []FileResponse) error {
@@ -39,5 +41,5 @@ func (s *CronScanner) Close() {
 		s.rules = nil
 	}
 
-	s.plugin.CronActions.UpdateRules(s.repository, s.rules)
+	s.plugin.CronActions.UpdateRules(s.repositoryID, s.repository, s.rules)
 }
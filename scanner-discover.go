@@ -1,35 +1,103 @@
 package main
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"net/http"
+	"regexp"
 	"strings"
+	"unicode/utf8"
 
-	"github.com/reeveci/plugin-gitea/encryption"
 	"github.com/reeveci/reeve-lib/conditions"
 	"github.com/reeveci/reeve-lib/schema"
 )
 
-func NewDiscoverScanner(plugin *GiteaPlugin, repository string, commit string, env map[string]schema.Env, pipelines *[]*schema.PipelineDefinition, defaultConditions map[string]schema.Condition) DocumentScanner {
+func NewDiscoverScanner(plugin *GiteaPlugin, repository string, ref, commit string, result *DiscoverResult, defaultConditions map[string]schema.Condition) DocumentScanner {
 	return &DiscoverScanner{
 		plugin:            plugin,
 		repository:        repository,
+		ref:               ref,
 		commit:            commit,
-		env:               env,
-		pipelines:         pipelines,
+		result:            result,
 		defaultConditions: defaultConditions,
 	}
 }
 
+func NewDiscoverResult(env map[string]schema.Env) *DiscoverResult {
+	return &DiscoverResult{
+		Env:         env,
+		PipelineEnv: make(map[string]map[string]schema.Env),
+		SecretFiles: make(map[string]SecretFile),
+
+		FailedSecrets: make(map[string]string),
+		Pipelines:     make([]*schema.PipelineDefinition, 0),
+		Components:    make(map[string][]*regexp.Regexp),
+	}
+}
+
+// DiscoverResult collects everything a DiscoverScanner finds in a repository.
+// PipelineEnv holds variables and secrets that are restricted to specific pipelines, indexed by the lower case pipeline name.
+// SecretFiles holds the files to be created for secrets, indexed by the env key of their content.
+// FailedSecrets holds the errors of secrets that could not be used, indexed by the secret's name.
+type DiscoverResult struct {
+	Env         map[string]schema.Env
+	PipelineEnv map[string]map[string]schema.Env
+	SecretFiles map[string]SecretFile
+
+	FailedSecrets map[string]string
+	Pipelines     []*schema.PipelineDefinition
+	Components    map[string][]*regexp.Regexp
+	PathFilters   []PathFilter
+}
+
+// EnvFor returns the environment of a pipeline, consisting of the shared environment and the entries restricted to the pipeline.
+func (r *DiscoverResult) EnvFor(pipeline string) map[string]schema.Env {
+	restricted := r.PipelineEnv[strings.ToLower(pipeline)]
+	if len(restricted) == 0 {
+		return r.Env
+	}
+
+	env := make(map[string]schema.Env, len(r.Env)+len(restricted))
+	for key, value := range r.Env {
+		env[key] = value
+	}
+	for key, value := range restricted {
+		env[key] = value
+	}
+	if token, ok := r.Env["__GIT_TOKEN"]; ok {
+		env["__GIT_TOKEN"] = token
+	}
+	return env
+}
+
+// WithholdSecrets removes all secrets from the result.
+func (r *DiscoverResult) WithholdSecrets() {
+	withholdSecrets(r.Env)
+	for _, env := range r.PipelineEnv {
+		withholdSecrets(env)
+	}
+}
+
+func withholdSecrets(env map[string]schema.Env) {
+	for key, value := range env {
+		if value.Secret {
+			delete(env, key)
+		}
+	}
+}
+
 type DiscoverScanner struct {
 	plugin            *GiteaPlugin
 	repository        string
+	ref               string
 	commit            string
-	env               map[string]schema.Env
-	pipelines         *[]*schema.PipelineDefinition
+	result            *DiscoverResult
 	defaultConditions map[string]schema.Condition
 
-	readme string
+	readme       string
+	repositoryID int64
 }
 
 func (s *DiscoverScanner) Init(rootFiles []FileResponse) error {
@@ -73,27 +141,73 @@ func (s *DiscoverScanner) Scan(document *SourceDocument) error {
 			pipeline.Description += s.readme
 		}
 
+		if err := s.reportPlaintextSecrets(document, LintPipelineParams(&pipeline)); err != nil {
+			return err
+		}
+
+		if err := s.applyPathFilters(&pipeline, document.PathFilters); err != nil {
+			return fmt.Errorf("error parsing %s from repository %s - pipeline %s - %s", document.SourceFile, s.repository, pipeline.Name, err)
+		}
+
 		conditions.ApplyDefaults(&pipeline.When, s.defaultConditions)
 
-		*s.pipelines = append(*s.pipelines, &pipeline)
+		s.result.Pipelines = append(s.result.Pipelines, &pipeline)
 
 	case "variable":
-		s.env[document.Name] = schema.Env{
+		if kind, ok := DetectCredential(document.Name, document.Value); ok {
+			if err := s.reportPlaintextSecrets(document, []LintFinding{{Location: "value", Kind: kind}}); err != nil {
+				return err
+			}
+		}
+		if ok, err := s.matchRef(document); !ok || err != nil {
+			return err
+		}
+		s.setEnv(document, document.Name, schema.Env{
 			Value:    document.Value,
 			Priority: 0,
 			Secret:   false,
-		}
+		})
 
 	case "secret":
-		decryptedValue, err := encryption.DecryptSecret(s.plugin.SecretKey, document.Value)
+		if ok, err := s.matchRef(document); !ok || err != nil {
+			return err
+		}
+		return s.addSecret(document, document.Name, document.Value, document.SourceFile)
+
+	case "secrets":
+		if document.Path == "" {
+			return fmt.Errorf("error parsing %s from repository %s - secrets without path", document.SourceFile, s.repository)
+		}
+
+		if document.File != "" {
+			return fmt.Errorf("error parsing %s from repository %s - secrets files do not support file", document.SourceFile, s.repository)
+		}
+		if ok, err := s.matchRef(document); !ok || err != nil {
+			return err
+		}
+
+		entries, err := s.fetchSecretsFile(document.Path)
 		if err != nil {
-			return fmt.Errorf("error decrypting secret %s in %s from repository %s - %s", document.Name, document.SourceFile, s.repository, err)
+			return fmt.Errorf("error loading secrets file %s (included by %s) from repository %s - %s", document.Path, document.SourceFile, s.repository, err)
 		}
-		s.env[document.Name] = schema.Env{
-			Value:    decryptedValue,
-			Priority: 0,
-			Secret:   true,
+		for _, entry := range entries {
+			if err := s.addSecret(document, entry.Name, entry.Value, fmt.Sprintf("%s (line %v)", document.Path, entry.Line)); err != nil {
+				return err
+			}
+		}
+
+	case "component":
+		if document.Name == "" {
+			return fmt.Errorf("error parsing %s from repository %s - component without name", document.SourceFile, s.repository)
+		}
+		if len(document.Paths) == 0 {
+			return fmt.Errorf("error parsing %s from repository %s - component %s without paths", document.SourceFile, s.repository, document.Name)
+		}
+		patterns, err := CompileGlobs(document.Paths)
+		if err != nil {
+			return fmt.Errorf("error parsing %s from repository %s - component %s - %s", document.SourceFile, s.repository, document.Name, err)
 		}
+		s.result.Components[document.Name] = append(s.result.Components[document.Name], patterns...)
 
 	case "trigger":
 
@@ -104,6 +218,220 @@ func (s *DiscoverScanner) Scan(document *SourceDocument) error {
 	return nil
 }
 
+// reportPlaintextSecrets logs a warning for each value of a document that looks like a plaintext credential.
+// If plaintext secrets are rejected, an error is returned instead.
+func (s *DiscoverScanner) reportPlaintextSecrets(document *SourceDocument, findings []LintFinding) error {
+	for _, finding := range findings {
+		message := fmt.Sprintf("%s %s in %s from repository %s - %s looks like a plaintext %s, consider using a secret", document.Type, document.Name, document.SourceFile, s.repository, finding.Location, finding.Kind)
+		if s.plugin.RejectPlaintextSecrets {
+			return fmt.Errorf("error parsing %s", message)
+		}
+		s.plugin.Log.Warn(message)
+	}
+	return nil
+}
+
+// matchRef reports whether the `refs` and `branches` restrictions of a variable or secret document permit its usage for the scanned ref.
+// If both are specified, matching either of them is sufficient.
+func (s *DiscoverScanner) matchRef(document *SourceDocument) (bool, error) {
+	if len(document.Refs) == 0 && len(document.Branches) == 0 {
+		return true, nil
+	}
+
+	refs, err := CompileGlobs(document.Refs)
+	if err != nil {
+		return false, fmt.Errorf("error parsing %s from repository %s - %s %s - %s", document.SourceFile, s.repository, document.Type, document.Name, err)
+	}
+	branches, err := CompileGlobs(document.Branches)
+	if err != nil {
+		return false, fmt.Errorf("error parsing %s from repository %s - %s %s - %s", document.SourceFile, s.repository, document.Type, document.Name, err)
+	}
+
+	if MatchAny(refs, s.ref) {
+		return true, nil
+	}
+	if strings.HasPrefix(s.ref, "refs/heads/") && MatchAny(branches, strings.TrimPrefix(s.ref, "refs/heads/")) {
+		return true, nil
+	}
+	return false, nil
+}
+
+// setEnv adds a variable or secret to the environment of all pipelines, or only to the pipelines named by the document.
+func (s *DiscoverScanner) setEnv(document *SourceDocument, name string, value schema.Env) {
+	if len(document.Pipelines) == 0 {
+		s.result.Env[name] = value
+		return
+	}
+
+	for _, pipeline := range document.Pipelines {
+		key := strings.ToLower(pipeline)
+		if s.result.PipelineEnv[key] == nil {
+			s.result.PipelineEnv[key] = make(map[string]schema.Env)
+		}
+		s.result.PipelineEnv[key][name] = value
+	}
+}
+
+// secretError returns the error of a secret that cannot be used.
+// If secret errors are tolerated, the error is recorded and logged instead, so that the secret is missing from the pipelines' environment.
+func (s *DiscoverScanner) secretError(name string, err error) error {
+	if !s.plugin.TolerateSecretErrors {
+		return err
+	}
+
+	s.plugin.Log.Warn(err.Error())
+	s.result.FailedSecrets[name] = err.Error()
+	return nil
+}
+
+// addSecret decrypts a secret and adds it to the environment, unless its scope does not permit its usage.
+func (s *DiscoverScanner) addSecret(document *SourceDocument, name, value, source string) error {
+	decryptedValue, header, err := s.plugin.Keyring.Decrypt(value)
+	if err != nil {
+		return s.secretError(name, fmt.Errorf("error decrypting secret %s in %s from repository %s - %s", name, source, s.repository, err))
+	}
+	var repositoryID int64
+	if header.Scope.RepositoryID != 0 {
+		if repositoryID, err = s.fetchRepositoryID(); err != nil {
+			return s.secretError(name, fmt.Errorf("error checking scope of secret %s in %s from repository %s - %s", name, source, s.repository, err))
+		}
+	}
+	if err := checkSecretScope(header.Scope, repositoryID, s.repository, s.ref); err != nil {
+		s.plugin.Log.Warn(fmt.Sprintf("withholding secret %s in %s from repository %s for %s - %s", name, source, s.repository, s.ref, err))
+		return nil
+	}
+
+	content, err := DecodeSecret(decryptedValue, document.Encoding)
+	if err != nil {
+		return s.secretError(name, fmt.Errorf("error decoding secret %s in %s from repository %s - %s", name, source, s.repository, err))
+	}
+
+	if document.Type == "secret" && document.File != "" {
+		file, err := NewSecretFile(document.File, document.Mode)
+		if err != nil {
+			return fmt.Errorf("error parsing %s from repository %s - secret %s - %s", document.SourceFile, s.repository, name, err)
+		}
+		s.result.SecretFiles[SECRET_FILE_ENV_PREFIX+name] = file
+
+		s.setEnv(document, SECRET_FILE_ENV_PREFIX+name, schema.Env{
+			Value:    base64.StdEncoding.EncodeToString(content),
+			Priority: 0,
+			Secret:   true,
+		})
+		s.setEnv(document, name, schema.Env{
+			Value:    file.Path,
+			Priority: 0,
+			Secret:   false,
+		})
+		return nil
+	}
+
+	// binary content cannot be passed as env variable, it must be provided as file instead
+	if !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		return s.secretError(name, fmt.Errorf("error decoding secret %s in %s from repository %s - binary content requires file", name, source, s.repository))
+	}
+
+	s.setEnv(document, name, schema.Env{
+		Value:    string(content),
+		Priority: 0,
+		Secret:   true,
+	})
+	return nil
+}
+
+// fetchRepositoryID fetches the ID of the repository once, since secrets are bound to the repository by ID.
+func (s *DiscoverScanner) fetchRepositoryID() (int64, error) {
+	if s.repositoryID != 0 {
+		return s.repositoryID, nil
+	}
+
+	repo, err := s.plugin.Scanner.FetchRepository(s.repository)
+	if err != nil {
+		return 0, err
+	}
+	if repo == nil {
+		return 0, fmt.Errorf("repository %s not found", s.repository)
+	}
+
+	s.repositoryID = repo.ID
+	return s.repositoryID, nil
+}
+
+func (s *DiscoverScanner) fetchSecretsFile(file string) ([]SecretEntry, error) {
+	resp, err := s.plugin.FetchRepoFileContent(s.repository, file, s.commit)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching file failed (status %v) - %s", resp.StatusCode, string(content))
+	}
+
+	return ParseSecretsFile(file, content)
+}
+
+// applyPathFilters translates the `paths` and `paths-ignore` shorthands of a pipeline and its steps into conditions.
+// Each shorthand is registered as a path filter, which is evaluated against the changed files once they are known,
+// and referenced by a condition on the filter's fact.
+func (s *DiscoverScanner) applyPathFilters(pipeline *schema.PipelineDefinition, filters PathFilters) error {
+	if err := s.applyPathFilter(&pipeline.When, filters.Pipeline); err != nil {
+		return err
+	}
+
+	if len(filters.Steps) > 0 {
+		steps := make([]schema.Step, len(pipeline.Steps))
+		copy(steps, pipeline.Steps)
+		pipeline.Steps = steps
+
+		for i := range pipeline.Steps {
+			if i >= len(filters.Steps) {
+				break
+			}
+			if err := s.applyPathFilter(&pipeline.Steps[i].When, filters.Steps[i]); err != nil {
+				return fmt.Errorf("step %s - %s", pipeline.Steps[i].Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *DiscoverScanner) applyPathFilter(when *map[string]schema.Condition, patterns PathPatterns) error {
+	if patterns.Empty() {
+		return nil
+	}
+
+	paths, err := CompileGlobs(patterns.Paths)
+	if err != nil {
+		return err
+	}
+	pathsIgnore, err := CompileGlobs(patterns.PathsIgnore)
+	if err != nil {
+		return err
+	}
+
+	filter := PathFilter{
+		Key:         fmt.Sprintf("%s%v", PATH_FILTER_FACT_PREFIX, len(s.result.PathFilters)),
+		Paths:       paths,
+		PathsIgnore: pathsIgnore,
+	}
+	s.result.PathFilters = append(s.result.PathFilters, filter)
+
+	newWhen := make(map[string]schema.Condition, len(*when)+1)
+	for key, condition := range *when {
+		newWhen[key] = condition
+	}
+	newWhen[filter.Key] = schema.Condition{Include: []string{"true"}}
+	*when = newWhen
+
+	return nil
+}
+
 func (s *DiscoverScanner) Done() {}
 
 func (s *DiscoverScanner) Close() {}
@@ -8,19 +8,21 @@ import (
 	"github.com/reeveci/reeve-lib/schema"
 )
 
-func NewWebUIScanner(plugin *GiteaPlugin, repository string) DocumentScanner {
+func NewWebUIScanner(plugin *GiteaPlugin, repositoryID int64, repository string) DocumentScanner {
 	return &WebUIScanner{
-		plugin:     plugin,
-		repository: repository,
-		actions:    make(map[string]bool),
+		plugin:       plugin,
+		repositoryID: repositoryID,
+		repository:   repository,
+		actions:      make(map[string]bool),
 	}
 }
 
 type WebUIScanner struct {
-	plugin     *GiteaPlugin
-	repository string
-	actions    map[string]bool
-	done       bool
+	plugin       *GiteaPlugin
+	repositoryID int64
+	repository   string
+	actions      map[string]bool
+	done         bool
 }
 
 func (s *WebUIScanner) Init(rootFiles []FileResponse) error {
@@ -50,7 +52,7 @@ func (s *WebUIScanner) Done() {
 
 func (s *WebUIScanner) Close() {
 	bundle := ActionBundle{
-		BundleID: "repo:" + s.repository,
+		BundleID: RepositoryBundleID(s.repositoryID),
 	}
 
 	if s.done {
@@ -81,6 +83,12 @@ func (s *WebUIScanner) Close() {
 	SendActionBundleMessage(s.plugin, bundle)
 }
 
+// RepositoryBundleID returns the WebUI action bundle ID for a repository.
+// Bundles are identified by repository ID, so that they are not affected by renaming or transferring repositories.
+func RepositoryBundleID(repositoryID int64) string {
+	return fmt.Sprintf("repo:%v", repositoryID)
+}
+
 type ActionBundle struct {
 	BundleID string   `json:"bundleID"`
 	Actions  []Action `json:"actions"`
@@ -47,7 +47,7 @@ type Scanner struct {
 
 	lock       sync.Mutex
 	closed     bool
-	knownRepos map[string]bool
+	knownRepos map[int64]string
 }
 
 func (s *Scanner) Close() {
@@ -153,9 +153,49 @@ func (s *Scanner) Search(search string) (result SearchResult, err error) {
 		}
 	}
 
+	result = s.plugin.Filter.Filter(result)
 	return
 }
 
+// Fetch a repository's details.
+// If the repository was not found, response and error are nil.
+func (s *Scanner) FetchRepository(repository string) (*RepositoryResponse, error) {
+	reponame, err := pathEscapeRepository(repository)
+	if err != nil {
+		return nil, fmt.Errorf("fetching repository %s failed - %s", repository, err)
+	}
+
+	var repositoryResponse RepositoryResponse
+	status, err := s.plugin.RequestAPI(http.MethodGet, "repos/"+reponame, nil, &repositoryResponse)
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching repository %s failed - %s", repository, err)
+	}
+
+	return &repositoryResponse, nil
+}
+
+// Check whether a repository is accepted by the configured repository filter.
+// Repository details are only fetched if required by the filter.
+func (s *Scanner) FilterRepository(repository string) (bool, error) {
+	if !s.plugin.Filter.MatchName(repository) {
+		return false, nil
+	}
+
+	if !s.plugin.Filter.NeedsDetails() {
+		return true, nil
+	}
+
+	repo, err := s.FetchRepository(repository)
+	if err != nil || repo == nil {
+		return false, err
+	}
+
+	return s.plugin.Filter.Match(*repo), nil
+}
+
 func (s *Scanner) FetchCommit(repository, branch string) (*CommitResponse, error) {
 	reponame, err := pathEscapeRepository(repository)
 	if err != nil {
@@ -188,6 +228,70 @@ func (s *Scanner) FetchCommit(repository, branch string) (*CommitResponse, error
 	return &commitResponse, nil
 }
 
+// Fetch the details of a commit.
+// If the commit was not found, response and error are nil.
+func (s *Scanner) FetchCommitDetails(repository, commit string) (*CommitDetails, error) {
+	reponame, err := pathEscapeRepository(repository)
+	if err != nil {
+		return nil, fmt.Errorf("fetching commit %s from %s failed - %s", commit, repository, err)
+	}
+
+	var commitResponse GitCommitResponse
+	status, err := s.plugin.RequestAPI(http.MethodGet, fmt.Sprintf("repos/%s/git/commits/%s?stat=false&files=false", reponame, url.PathEscape(commit)), nil, &commitResponse)
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching commit %s from %s failed - %s", commit, repository, err)
+	}
+
+	details := commitResponse.Details()
+	return &details, nil
+}
+
+// Fetch a tag from a repository.
+// If the tag was not found, response and error are nil.
+func (s *Scanner) FetchTag(repository, tag string) (*TagResponse, error) {
+	reponame, err := pathEscapeRepository(repository)
+	if err != nil {
+		return nil, fmt.Errorf("fetching tag %s from %s failed - %s", tag, repository, err)
+	}
+
+	var tagResponse TagResponse
+	status, err := s.plugin.RequestAPI(http.MethodGet, fmt.Sprintf("repos/%s/tags/%s", reponame, url.PathEscape(tag)), nil, &tagResponse)
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching tag %s from %s failed - %s", tag, repository, err)
+	}
+
+	return &tagResponse, nil
+}
+
+// Fetch all tags of a repository, starting with the most recently created tag.
+func (s *Scanner) FetchTags(repository string) ([]TagResponse, error) {
+	reponame, err := pathEscapeRepository(repository)
+	if err != nil {
+		return nil, fmt.Errorf("fetching tags from %s failed - %s", repository, err)
+	}
+
+	const limit = 50
+	var result []TagResponse
+	for page := 1; ; page++ {
+		var tagsResponse []TagResponse
+		_, err := s.plugin.RequestAPI(http.MethodGet, fmt.Sprintf("repos/%s/tags?page=%v&limit=%v", reponame, page, limit), nil, &tagsResponse)
+		if err != nil {
+			return nil, fmt.Errorf("fetching tags from %s failed - %s", repository, err)
+		}
+
+		result = append(result, tagsResponse...)
+		if len(tagsResponse) < limit {
+			return result, nil
+		}
+	}
+}
+
 func (s *Scanner) TestRepositoryAccess(repository string) (bool, error) {
 	userUrl := fmt.Sprintf("%sapi/v1/user", s.plugin.InternalUrl)
 	req, err := http.NewRequest(http.MethodGet, userUrl, nil)
@@ -450,7 +554,8 @@ func (s *Scanner) readRepositoryConfig(repository string, configFile string, com
 }
 
 type ScanRequest struct {
-	Type, Repository string
+	Type       string
+	Repository RepositoryResponse
 }
 
 func (s *Scanner) Scan() {
@@ -463,14 +568,25 @@ func (s *Scanner) Scan() {
 	s.queue <- ScanRequest{Type: "scan"}
 }
 
-func (s *Scanner) Notify(repository string) {
+func (s *Scanner) Notify(repo RepositoryResponse) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return
+	}
+
+	s.queue <- ScanRequest{Type: "notify", Repository: repo}
+}
+
+// Drop removes all state associated with a repository, e.g. because it has been deleted.
+func (s *Scanner) Drop(repo RepositoryResponse) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	if s.closed {
 		return
 	}
 
-	s.queue <- ScanRequest{Type: "notify", Repository: repository}
+	s.queue <- ScanRequest{Type: "drop", Repository: repo}
 }
 
 func (s *Scanner) handleQueue() {
@@ -486,6 +602,11 @@ func (s *Scanner) handleQueue() {
 
 		case "notify":
 			s.notify(request.Repository)
+
+		case "drop":
+			if request.Repository.ID != 0 {
+				s.drop(request.Repository.ID, request.Repository.FullName)
+			}
 		}
 	}
 }
@@ -499,32 +620,43 @@ func (s *Scanner) scan() {
 		return
 	}
 
-	currentRepos := make(map[string]bool, len(searchResult))
+	currentRepos := make(map[int64]bool, len(searchResult))
 	for _, repo := range searchResult {
-		currentRepos[repo.FullName] = true
-	}
-	for repository := range s.knownRepos {
-		if !currentRepos[repository] {
-			s.plugin.Log.Info(fmt.Sprintf("dropping repository %s", repository))
-			SendActionBundleMessage(s.plugin, ActionBundle{
-				BundleID: "repo:" + repository,
-				Actions:  nil,
-			})
+		currentRepos[repo.ID] = true
+	}
+	for id := range s.knownRepos {
+		if !currentRepos[id] {
+			s.drop(id, "")
 		}
 	}
-	s.knownRepos = currentRepos
 
 	for _, repo := range searchResult {
-		s.notify(repo.FullName)
+		s.notify(repo)
 	}
 }
 
-func (s *Scanner) notify(repository string) {
-	if repository == "" {
+func (s *Scanner) notify(repo RepositoryResponse) {
+	if repo.ID == 0 || repo.FullName == "" {
 		return
 	}
 
-	s.plugin.Log.Info(fmt.Sprintf("scanning repository %s", repository))
+	if !s.plugin.Filter.Match(repo) {
+		if _, known := s.knownRepos[repo.ID]; known {
+			s.drop(repo.ID, repo.FullName)
+		}
+		return
+	}
+
+	if s.knownRepos == nil {
+		s.knownRepos = make(map[int64]string)
+	}
+	if previousName, known := s.knownRepos[repo.ID]; known && previousName != repo.FullName {
+		s.plugin.Log.Info(fmt.Sprintf("repository %s was renamed to %s", previousName, repo.FullName))
+		s.plugin.CronActions.RenameRepository(repo.ID, repo.FullName)
+	}
+	s.knownRepos[repo.ID] = repo.FullName
+
+	s.plugin.Log.Info(fmt.Sprintf("scanning repository %s", repo.FullName))
 
 	scanners := make([]DocumentScanner, 0, 2)
 
@@ -532,12 +664,29 @@ func (s *Scanner) notify(repository string) {
 	hasUI := s.plugin.WebUIPresent
 	s.plugin.Unlock()
 	if hasUI {
-		scanners = append(scanners, NewWebUIScanner(s.plugin, repository))
+		scanners = append(scanners, NewWebUIScanner(s.plugin, repo.ID, repo.FullName))
+	}
+
+	scanners = append(scanners, NewCronScanner(s.plugin, repo.ID, repo.FullName))
+
+	s.ScanRepository(repo.FullName, "", scanners...)
+}
+
+// drop removes all state associated with a repository that is no longer available.
+// The repository name is only used if the repository is not known yet.
+func (s *Scanner) drop(id int64, repository string) {
+	if knownName, known := s.knownRepos[id]; known {
+		repository = knownName
 	}
+	delete(s.knownRepos, id)
 
-	scanners = append(scanners, NewCronScanner(s.plugin, repository))
+	s.plugin.Log.Info(fmt.Sprintf("dropping repository %s", repository))
 
-	s.ScanRepository(repository, "", scanners...)
+	SendActionBundleMessage(s.plugin, ActionBundle{
+		BundleID: RepositoryBundleID(id),
+		Actions:  nil,
+	})
+	s.plugin.CronActions.UpdateRules(id, repository, nil)
 }
 
 type DocumentScanner interface {
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/reeveci/reeve-lib/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// SecretEntry is a single encrypted value of a secrets file.
+type SecretEntry struct {
+	Name  string
+	Value string
+	Line  int
+}
+
+// ParseSecretsFile parses a file of encrypted values. Files ending with `.env` are parsed as dotenv files,
+// files ending with `.yaml` or `.yml` as flat YAML mappings.
+func ParseSecretsFile(filename string, content []byte) ([]SecretEntry, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".env":
+		return parseDotenvSecrets(content)
+
+	case ".yaml", ".yml":
+		return parseYAMLSecrets(content)
+
+	default:
+		return nil, fmt.Errorf("invalid file extension, please use one of .env, .yaml, .yml")
+	}
+}
+
+func parseDotenvSecrets(content []byte) ([]SecretEntry, error) {
+	var result []SecretEntry
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("line %v - expected NAME=value", line)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "" {
+			return nil, fmt.Errorf("line %v - missing name", line)
+		}
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		result = append(result, SecretEntry{Name: name, Value: value, Line: line})
+	}
+
+	return result, scanner.Err()
+}
+
+func parseYAMLSecrets(content []byte) ([]SecretEntry, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+
+	node := resolveAlias(root.Content[0])
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping of names to encrypted values")
+	}
+
+	result := make([]SecretEntry, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolveAlias(node.Content[i+1])
+		if value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %v - value of %s is not a string", key.Line, key.Value)
+		}
+		result = append(result, SecretEntry{Name: key.Value, Value: value.Value, Line: key.Line})
+	}
+
+	return result, nil
+}
+
+// SECRET_FILE_ENV_PREFIX is the prefix of the env keys holding the base64 encoded content of secret files.
+const SECRET_FILE_ENV_PREFIX = "__SECRET_FILE_"
+
+// SecretFile describes where the content of a secret is written to before the pipeline runs.
+type SecretFile struct {
+	Path string
+	Mode string
+}
+
+func NewSecretFile(filePath, mode string) (SecretFile, error) {
+	if mode == "" {
+		mode = "0600"
+	}
+	if _, err := strconv.ParseUint(mode, 8, 32); err != nil {
+		return SecretFile{}, fmt.Errorf("invalid file mode \"%s\"", mode)
+	}
+
+	cleaned := path.Clean(filePath)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") || path.IsAbs(cleaned) {
+		return SecretFile{}, fmt.Errorf("invalid file path \"%s\"", filePath)
+	}
+
+	return SecretFile{Path: cleaned, Mode: mode}, nil
+}
+
+// DecodeSecret decodes a decrypted secret value according to its encoding, which is either `text` (default) or `base64`.
+func DecodeSecret(value, encoding string) ([]byte, error) {
+	switch strings.ToLower(encoding) {
+	case "", "text":
+		return []byte(value), nil
+
+	case "base64":
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+
+	default:
+		return nil, fmt.Errorf("unknown encoding %s", encoding)
+	}
+}
+
+// SecretFileParams creates the setup task params for all secret files available in env.
+// For each file, the params `GIT_SECRET_FILE_<n>` (base64 encoded content), `GIT_SECRET_FILE_<n>_PATH` and `GIT_SECRET_FILE_<n>_MODE` are created,
+// numbered from 1 in the order of the file paths.
+func SecretFileParams(files map[string]SecretFile, env map[string]schema.Env) map[string]schema.RawParam {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		if _, ok := env[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return files[keys[i]].Path < files[keys[j]].Path })
+
+	params := make(map[string]schema.RawParam, 3*len(keys))
+	for i, key := range keys {
+		prefix := fmt.Sprintf("GIT_SECRET_FILE_%v", i+1)
+		params[prefix] = schema.EnvParam{Env: key}
+		params[prefix+"_PATH"] = schema.LiteralParam(files[key].Path)
+		params[prefix+"_MODE"] = schema.LiteralParam(files[key].Mode)
+	}
+	return params
+}
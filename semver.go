@@ -0,0 +1,109 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var semVerPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// SemVer is a semantic version as specified by https://semver.org, optionally prefixed with `v`.
+type SemVer struct {
+	Major, Minor, Patch uint64
+	Prerelease, Build   string
+}
+
+// ParseSemVer parses a semantic version, e.g. from a tag name.
+func ParseSemVer(version string) (result SemVer, ok bool) {
+	match := semVerPattern.FindStringSubmatch(version)
+	if match == nil {
+		return
+	}
+
+	var err error
+	if result.Major, err = strconv.ParseUint(match[1], 10, 64); err != nil {
+		return
+	}
+	if result.Minor, err = strconv.ParseUint(match[2], 10, 64); err != nil {
+		return
+	}
+	if result.Patch, err = strconv.ParseUint(match[3], 10, 64); err != nil {
+		return
+	}
+	result.Prerelease = match[4]
+	result.Build = match[5]
+	return result, true
+}
+
+// String returns the version without prefix and build metadata.
+func (v SemVer) String() string {
+	result := strconv.FormatUint(v.Major, 10) + "." + strconv.FormatUint(v.Minor, 10) + "." + strconv.FormatUint(v.Patch, 10)
+	if v.Prerelease != "" {
+		result += "-" + v.Prerelease
+	}
+	return result
+}
+
+// Compare returns -1, 0 or 1 depending on whether v has a lower, the same or a higher precedence than other.
+// Build metadata is ignored.
+func (v SemVer) Compare(other SemVer) int {
+	for _, pair := range [][2]uint64{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if pair[0] < pair[1] {
+			return -1
+		}
+		if pair[0] > pair[1] {
+			return 1
+		}
+	}
+
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	}
+
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(other.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if result := comparePrereleaseIdentifier(a[i], b[i]); result != 0 {
+			return result
+		}
+	}
+
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func comparePrereleaseIdentifier(a, b string) int {
+	numA, errA := strconv.ParseUint(a, 10, 64)
+	numB, errB := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case errA == nil && errB == nil:
+		if numA < numB {
+			return -1
+		}
+		if numA > numB {
+			return 1
+		}
+		return 0
+
+	// numeric identifiers have lower precedence than alphanumeric identifiers
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+
+	default:
+		return strings.Compare(a, b)
+	}
+}
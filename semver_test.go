@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseSemVer(t *testing.T) {
+	tests := []struct {
+		version string
+		ok      bool
+		result  SemVer
+	}{
+		{"1.2.3", true, SemVer{Major: 1, Minor: 2, Patch: 3}},
+		{"v1.2.3-rc.1+build.5", true, SemVer{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1", Build: "build.5"}},
+		{"v0.0.0-alpha-1", true, SemVer{Prerelease: "alpha-1"}},
+		{"1.2", false, SemVer{}},
+		{"01.2.3", false, SemVer{}},
+		{"1.2.3-01", false, SemVer{}},
+		{"V1.2.3", false, SemVer{}},
+		{"release-1.2.3", false, SemVer{}},
+		{"1.2.99999999999999999999", false, SemVer{}},
+	}
+
+	for _, test := range tests {
+		result, ok := ParseSemVer(test.version)
+		if ok != test.ok || ok && result != test.result {
+			t.Errorf("%s: expected %+v (%v), got %+v (%v)", test.version, test.result, test.ok, result, ok)
+		}
+	}
+}
+
+func TestSemVerCompare(t *testing.T) {
+	// ordered by precedence as specified by https://semver.org
+	versions := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0"}
+
+	for i, a := range versions {
+		for j, b := range versions {
+			va, _ := ParseSemVer(a)
+			vb, _ := ParseSemVer(b)
+
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			if result := va.Compare(vb); result != expected {
+				t.Errorf("%s compared to %s: expected %v, got %v", a, b, expected, result)
+			}
+		}
+	}
+
+	a, _ := ParseSemVer("v1.0.0+build.1")
+	b, _ := ParseSemVer("1.0.0+build.2")
+	if result := a.Compare(b); result != 0 {
+		t.Errorf("expected build metadata to be ignored, got %v", result)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/reeveci/reeve-lib/schema"
+)
+
+// TRAILER_FACT_PREFIX is the prefix of the facts generated for commit message trailers.
+const TRAILER_FACT_PREFIX = "trailer:"
+
+// addMissingTrailerFacts sets the trailer facts referenced by pipeline or step conditions to an empty string if the trailer is absent,
+// so that conditions on trailers do not match.
+func addMissingTrailerFacts(facts map[string]schema.Fact, pipelines []*schema.PipelineDefinition) {
+	add := func(when map[string]schema.Condition) {
+		for key := range when {
+			if _, ok := facts[key]; !ok && strings.HasPrefix(key, TRAILER_FACT_PREFIX) {
+				facts[key] = schema.Fact{""}
+			}
+		}
+	}
+
+	for _, pipeline := range pipelines {
+		add(pipeline.When)
+		for _, step := range pipeline.Steps {
+			add(step.When)
+		}
+	}
+}
+
+var trailerPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*)\s*:\s*(.*)$`)
+var skipPattern = regexp.MustCompile(`(?i)\[skip ([^\]]+)\]`)
+var paragraphSeparator = regexp.MustCompile(`\n\s*\n`)
+
+// ParseTrailers parses the trailers of a commit message, e.g. `Reeve-Skip: lint`.
+// Trailers are read from the last paragraph of the message, if all of its lines are trailers.
+// Keys are converted to lower case.
+func ParseTrailers(message string) map[string][]string {
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	paragraphs := paragraphSeparator.Split(message, -1)
+	if len(paragraphs) < 2 {
+		return nil
+	}
+
+	result := make(map[string][]string)
+	var lastKey string
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		// continuation lines start with whitespace
+		if lastKey != "" && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			values := result[lastKey]
+			values[len(values)-1] += " " + strings.TrimSpace(line)
+			continue
+		}
+
+		match := trailerPattern.FindStringSubmatch(line)
+		if match == nil {
+			return nil
+		}
+		lastKey = strings.ToLower(match[1])
+		result[lastKey] = append(result[lastKey], strings.TrimSpace(match[2]))
+	}
+
+	return result
+}
+
+// collectTrailers merges the trailers of multiple commit messages.
+func collectTrailers(messages []string) map[string][]string {
+	result := make(map[string][]string)
+	for _, message := range messages {
+		for key, values := range ParseTrailers(message) {
+			result[key] = append(result[key], values...)
+		}
+	}
+	return result
+}
+
+// hasGlobalSkipDirective reports whether any of the commit messages contains `[skip ci]` or `[ci skip]`.
+func hasGlobalSkipDirective(messages []string) bool {
+	for _, message := range messages {
+		message = strings.ToLower(message)
+		if strings.Contains(message, "[skip ci]") || strings.Contains(message, "[ci skip]") {
+			return true
+		}
+	}
+	return false
+}
+
+// collectSkippedPipelines determines the pipelines that should be skipped
+// based on `[skip <pipeline>]` directives and `Reeve-Skip: <pipeline>, <pipeline>` trailers in commit messages.
+// Pipeline names may contain spaces, so trailer values are only split on commas.
+func collectSkippedPipelines(messages []string, trailers map[string][]string) map[string]bool {
+	result := make(map[string]bool)
+	for _, message := range messages {
+		for _, match := range skipPattern.FindAllStringSubmatch(message, -1) {
+			if name := strings.TrimSpace(match[1]); name != "" {
+				result[strings.ToLower(name)] = true
+			}
+		}
+	}
+	for _, value := range trailers["reeve-skip"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				result[strings.ToLower(name)] = true
+			}
+		}
+	}
+	return result
+}
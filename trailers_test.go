@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTrailers(t *testing.T) {
+	tests := []struct {
+		message string
+		result  map[string][]string
+	}{
+		{"Fix build", nil},
+		{"Fix build\n\nReeve-Skip: lint", map[string][]string{"reeve-skip": {"lint"}}},
+		{"Fix build\r\n\r\nDescription\r\n\r\nSigned-off-by: A <a@example.com>\r\nsigned-off-by: B <b@example.com>", map[string][]string{"signed-off-by": {"A <a@example.com>", "B <b@example.com>"}}},
+		{"Fix build\n\nDeploy: staging\n  production", map[string][]string{"deploy": {"staging production"}}},
+		{"Fix build\n\nDeploy: staging\nThis is not a trailer", nil},
+		{"Fix build\n\nSee https://example.com: details", nil},
+	}
+
+	for _, test := range tests {
+		if result := ParseTrailers(test.message); !reflect.DeepEqual(result, test.result) {
+			t.Errorf("%q: expected %v, got %v", test.message, test.result, result)
+		}
+	}
+}
+
+func TestCollectSkippedPipelines(t *testing.T) {
+	messages := []string{"Update docs [skip lint] [skip Build Docs]", "Fix build\n\nReeve-Skip: test, Deploy Staging,"}
+	result := collectSkippedPipelines(messages, collectTrailers(messages))
+
+	expected := map[string]bool{"lint": true, "build docs": true, "test": true, "deploy staging": true}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
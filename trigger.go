@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/reeveci/reeve-lib/schema"
+)
+
+// NewTrigger creates a git trigger for a commit of a repository.
+func NewTrigger(triggerType string, repo RepositoryResponse, ref, commit, commitMessage string) schema.Trigger {
+	return map[string]string{
+		"type":          "git",
+		"trigger":       triggerType,
+		"ref":           ref,
+		"commit":        commit,
+		"commitMessage": commitMessage,
+		"repository":    repo.FullName,
+		"repositoryURL": repo.HtmlURL,
+		"cloneURL":      repo.CloneURL,
+		"defaultBranch": repo.DefaultBranch,
+		"owner":         repo.Owner.Login,
+		"visibility":    repositoryVisibility(repo),
+	}
+}
+
+func NewActionTrigger(action string, repo RepositoryResponse, ref, commit, commitMessage string) schema.Trigger {
+	trigger := NewTrigger("action", repo, ref, commit, commitMessage)
+	trigger["action"] = action
+	return trigger
+}
+
+// AddCommitDetails adds authors, committers and messages of the specified commits to a trigger.
+func AddCommitDetails(trigger schema.Trigger, commits ...CommitDetails) {
+	var authors, committers []string
+	messages := make([]string, 0, len(commits))
+	for _, commit := range commits {
+		authors = appendCommitUser(authors, commit.Author)
+		committers = appendCommitUser(committers, commit.Committer)
+		messages = append(messages, commit.Message)
+	}
+
+	trigger["authors"] = strings.Join(authors, "\n")
+	trigger["committers"] = strings.Join(committers, "\n")
+
+	if data, err := json.Marshal(messages); err == nil {
+		trigger["commitMessages"] = string(data)
+	}
+}
+
+// appendCommitUser adds the username and email address of a commit user to a list, unless they are already contained.
+func appendCommitUser(list []string, user CommitUser) []string {
+	for _, value := range []string{user.Username, user.Email} {
+		if value == "" {
+			continue
+		}
+
+		var found bool
+		for _, item := range list {
+			if item == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// parseCommitMessages reads the commit messages of a trigger.
+// For triggers without a list of commit messages, the head commit's message is returned.
+func parseCommitMessages(trigger schema.Trigger) []string {
+	var messages []string
+	if rawMessages := trigger["commitMessages"]; rawMessages != "" {
+		if err := json.Unmarshal([]byte(rawMessages), &messages); err == nil {
+			return messages
+		}
+	}
+
+	if message := trigger["commitMessage"]; message != "" {
+		return []string{message}
+	}
+	return nil
+}
+
+// splitTriggerList reads a newline separated list from a trigger field.
+// Empty fields result in a nil fact.
+func splitTriggerList(value string) schema.Fact {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, "\n")
+}
+
+func repositoryVisibility(repo RepositoryResponse) string {
+	switch {
+	case repo.Internal:
+		return "internal"
+	case repo.Private:
+		return "private"
+	default:
+		return "public"
+	}
+}
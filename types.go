@@ -3,22 +3,102 @@ package main
 import "github.com/reeveci/reeve-lib/schema"
 
 type Webhook struct {
-	Ref string `json:"ref"`
+	Ref    string `json:"ref"`
+	Before string `json:"before"`
+	After  string `json:"after"`
 
-	HeadCommit struct {
-		ID      string `json:"id"`
-		Message string `json:"message"`
-		ModifiedFiles
-	} `json:"head_commit"`
+	HeadCommit WebhookCommit `json:"head_commit"`
 
-	Commits []ModifiedFiles `json:"commits"`
+	Commits []WebhookCommit `json:"commits"`
 
-	Repository struct {
-		FullName      string `json:"full_name"`
-		HtmlURL       string `json:"html_url"`
-		CloneURL      string `json:"clone_url"`
-		DefaultBranch string `json:"default_branch"`
-	} `json:"repository"`
+	Pusher UserResponse `json:"pusher"`
+
+	Repository RepositoryResponse `json:"repository"`
+}
+
+func (w Webhook) ModifiedFiles() []ModifiedFiles {
+	result := make([]ModifiedFiles, len(w.Commits))
+	for i, commit := range w.Commits {
+		result[i] = commit.ModifiedFiles
+	}
+	return result
+}
+
+func (w Webhook) CommitDetails() []CommitDetails {
+	result := make([]CommitDetails, len(w.Commits))
+	for i, commit := range w.Commits {
+		result[i] = commit.CommitDetails
+	}
+	if len(result) == 0 && w.HeadCommit.ID != "" {
+		result = append(result, w.HeadCommit.CommitDetails)
+	}
+	return result
+}
+
+type WebhookCommit struct {
+	CommitDetails
+	ModifiedFiles
+}
+
+type CommitDetails struct {
+	ID        string     `json:"id"`
+	Message   string     `json:"message"`
+	Author    CommitUser `json:"author"`
+	Committer CommitUser `json:"committer"`
+}
+
+type CommitUser struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+type RefWebhook struct {
+	Sha        string             `json:"sha"`
+	Ref        string             `json:"ref"`
+	RefType    string             `json:"ref_type"`
+	Sender     UserResponse       `json:"sender"`
+	Repository RepositoryResponse `json:"repository"`
+}
+
+type ReleaseWebhook struct {
+	Action  string `json:"action"`
+	Release struct {
+		TagName    string `json:"tag_name"`
+		Name       string `json:"name"`
+		Body       string `json:"body"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+		Assets     []struct {
+			Name               string `json:"name"`
+			BrowserDownloadURL string `json:"browser_download_url"`
+		} `json:"assets"`
+	} `json:"release"`
+	Repository RepositoryResponse `json:"repository"`
+}
+
+type IssueCommentWebhook struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number      int64 `json:"number"`
+		PullRequest *struct {
+			Merged bool `json:"merged"`
+		} `json:"pull_request"`
+	} `json:"issue"`
+	Comment struct {
+		ID   int64  `json:"id"`
+		Body string `json:"body"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"comment"`
+	IsPull     bool               `json:"is_pull"`
+	Repository RepositoryResponse `json:"repository"`
+}
+
+type RepositoryWebhook struct {
+	Action     string             `json:"action"`
+	Repository RepositoryResponse `json:"repository"`
 }
 
 type ModifiedFiles struct {
@@ -30,11 +110,19 @@ type ModifiedFiles struct {
 type Document struct {
 	Type                      string `yaml:"type"`
 	schema.PipelineDefinition `yaml:",inline"`
-	Value                     string `yaml:"value"`
-	Cron                      string `yaml:"cron"`
-	Action                    string `yaml:"action"`
-	Path                      string `yaml:"path"`
-	TemplateData              any    `yaml:"templateData"`
+	Value                     string      `yaml:"value"`
+	Cron                      string      `yaml:"cron"`
+	Action                    string      `yaml:"action"`
+	Path                      string      `yaml:"path"`
+	Paths                     []string    `yaml:"paths"`
+	Refs                      []string    `yaml:"refs"`
+	Branches                  []string    `yaml:"branches"`
+	Pipelines                 []string    `yaml:"pipelines"`
+	Encoding                  string      `yaml:"encoding"`
+	File                      string      `yaml:"file"`
+	Mode                      string      `yaml:"mode"`
+	TemplateData              any         `yaml:"templateData"`
+	PathFilters               PathFilters `yaml:"-"`
 }
 
 type SourceDocument struct {
@@ -43,29 +131,125 @@ type SourceDocument struct {
 }
 
 type UserResponse struct {
-	ID int `json:"id"`
+	ID    int    `json:"id"`
+	Login string `json:"login"`
+}
+
+type PermissionResponse struct {
+	Permission string `json:"permission"`
 }
 
 type AssigneesResponse []UserResponse
 
-type SearchResult []struct {
-	FullName      string `json:"full_name"`
-	HtmlURL       string `json:"html_url"`
-	CloneURL      string `json:"clone_url"`
-	DefaultBranch string `json:"default_branch"`
+type RepositoryResponse struct {
+	ID            int64    `json:"id"`
+	FullName      string   `json:"full_name"`
+	HtmlURL       string   `json:"html_url"`
+	CloneURL      string   `json:"clone_url"`
+	DefaultBranch string   `json:"default_branch"`
+	Archived      bool     `json:"archived"`
+	Fork          bool     `json:"fork"`
+	Mirror        bool     `json:"mirror"`
+	Template      bool     `json:"template"`
+	Topics        []string `json:"topics"`
+	Private       bool     `json:"private"`
+	Internal      bool     `json:"internal"`
+	Owner         struct {
+		Login string `json:"login"`
+	} `json:"owner"`
 }
 
+type SearchResult []RepositoryResponse
+
 type SearchResponse struct {
 	Data SearchResult `json:"data"`
 }
 
 type CommitResponse struct {
+	Commit CommitDetails `json:"commit"`
+}
+
+type BranchResponse struct {
+	Name      string `json:"name"`
+	Protected bool   `json:"protected"`
+}
+
+type GitCommitResponse struct {
+	SHA    string `json:"sha"`
 	Commit struct {
-		ID      string `json:"id"`
-		Message string `json:"message"`
+		Message      string             `json:"message"`
+		Author       CommitUser         `json:"author"`
+		Committer    CommitUser         `json:"committer"`
+		Verification CommitVerification `json:"verification"`
+	} `json:"commit"`
+	Author    *UserResponse `json:"author"`
+	Committer *UserResponse `json:"committer"`
+}
+
+// Details converts the commit to the format used in webhooks.
+// Usernames are only available if the commit's author and committer are linked to Gitea users.
+func (c GitCommitResponse) Details() CommitDetails {
+	details := CommitDetails{
+		ID:        c.SHA,
+		Message:   c.Commit.Message,
+		Author:    CommitUser{Name: c.Commit.Author.Name, Email: c.Commit.Author.Email},
+		Committer: CommitUser{Name: c.Commit.Committer.Name, Email: c.Commit.Committer.Email},
+	}
+	if c.Author != nil {
+		details.Author.Username = c.Author.Login
+	}
+	if c.Committer != nil {
+		details.Committer.Username = c.Committer.Login
+	}
+	return details
+}
+
+type CommitVerification struct {
+	Verified bool   `json:"verified"`
+	Reason   string `json:"reason"`
+	Signer   *struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Username string `json:"username"`
+	} `json:"signer"`
+}
+
+type TagResponse struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Commit  struct {
+		Sha string `json:"sha"`
 	} `json:"commit"`
 }
 
+type PullRequestResponse struct {
+	Number int64 `json:"number"`
+	Head   struct {
+		Ref  string `json:"ref"`
+		Sha  string `json:"sha"`
+		Repo struct {
+			ID int64 `json:"id"`
+		} `json:"repo"`
+	} `json:"head"`
+	Base struct {
+		Sha  string `json:"sha"`
+		Repo struct {
+			ID int64 `json:"id"`
+		} `json:"repo"`
+	} `json:"base"`
+}
+
+type CompareResponse struct {
+	TotalCommits int `json:"total_commits"`
+	Commits      []struct {
+		Sha   string `json:"sha"`
+		Files []struct {
+			Filename string `json:"filename"`
+			Status   string `json:"status"`
+		} `json:"files"`
+	} `json:"commits"`
+}
+
 type ContentsResponse []FileResponse
 
 type FileResponse struct {
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -36,6 +37,19 @@ func defaultSetting(settings map[string]string, key, defaultValue string) (resul
 	return
 }
 
+func intSetting(settings map[string]string, key string, defaultValue int) (result int, err error) {
+	value := settings[key]
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	result, err = strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer setting %s: %s", key, value)
+	}
+	return
+}
+
 func parseBaseURL(baseURL string) (string, error) {
 	u, err := url.Parse(baseURL)
 	if err != nil {
@@ -46,10 +60,12 @@ func parseBaseURL(baseURL string) (string, error) {
 	return u.JoinPath("/").String(), nil
 }
 
-func collectFiles(webhook Webhook) []string {
+// collectFiles determines the files affected by a list of commits, ordered from newest to oldest.
+// Files that were both added and removed by the commits are omitted.
+func collectFiles(commits []ModifiedFiles) []string {
 	fileMap := make(map[string]struct{ Before, Now bool })
-	for i := len(webhook.Commits) - 1; i >= 0; i-- {
-		commit := webhook.Commits[i]
+	for i := len(commits) - 1; i >= 0; i-- {
+		commit := commits[i]
 
 		for _, file := range commit.Added {
 			item, ok := fileMap[file]
@@ -101,3 +117,33 @@ func pathEscapeRepository(repository string) (string, error) {
 	}
 	return strings.Join(parts, "/"), nil
 }
+
+// isZeroCommit reports whether a commit ID consists of zeros only, which Gitea uses for refs that do not exist.
+func isZeroCommit(commit string) bool {
+	return commit != "" && strings.Trim(commit, "0") == ""
+}
+
+// fullRefName converts a short branch or tag name to a full Git ref.
+func fullRefName(ref, refType string) string {
+	if strings.HasPrefix(ref, "refs/") {
+		return ref
+	}
+
+	switch refType {
+	case "branch":
+		return "refs/heads/" + ref
+	case "tag":
+		return "refs/tags/" + ref
+	default:
+		return ref
+	}
+}
+
+// pathEscapeRef escapes a Git ref or commit for usage in URL paths, keeping slashes as separators.
+func pathEscapeRef(ref string) string {
+	parts := strings.Split(ref, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Fetch a branch from a repository.
+// If the branch was not found, response and error are nil.
+func (s *Scanner) FetchBranch(repository, branch string) (*BranchResponse, error) {
+	reponame, err := pathEscapeRepository(repository)
+	if err != nil {
+		return nil, fmt.Errorf("fetching branch %s from %s failed - %s", branch, repository, err)
+	}
+
+	var branchResponse BranchResponse
+	status, err := s.plugin.RequestAPI(http.MethodGet, fmt.Sprintf("repos/%s/branches/%s", reponame, url.PathEscape(branch)), nil, &branchResponse)
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching branch %s from %s failed - %s", branch, repository, err)
+	}
+
+	return &branchResponse, nil
+}
+
+// Fetch the signature verification of a commit.
+func (s *Scanner) FetchCommitVerification(repository, commit string) (*CommitVerification, error) {
+	reponame, err := pathEscapeRepository(repository)
+	if err != nil {
+		return nil, fmt.Errorf("fetching verification of commit %s from %s failed - %s", commit, repository, err)
+	}
+
+	var commitResponse GitCommitResponse
+	_, err = s.plugin.RequestAPI(http.MethodGet, fmt.Sprintf("repos/%s/git/commits/%s?stat=false&files=false", reponame, url.PathEscape(commit)), nil, &commitResponse)
+	if err != nil {
+		return nil, fmt.Errorf("fetching verification of commit %s from %s failed - %s", commit, repository, err)
+	}
+
+	return &commitResponse.Commit.Verification, nil
+}
+
+// Determine whether a ref is a protected branch.
+// Refs other than branches are never considered to be protected. Errors are logged and result in an unprotected ref.
+func (p *GiteaPlugin) isProtectedRef(repository, ref string) bool {
+	if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+		branchResponse, err := p.Scanner.FetchBranch(repository, branch)
+		if err != nil {
+			p.Log.Warn(fmt.Sprintf("treating branch %s in %s as unprotected - %s", branch, repository, err))
+			return false
+		}
+		return branchResponse != nil && branchResponse.Protected
+	}
+	return false
+}
+
+// Determine whether a commit carries a verified signature. Errors are logged and result in an unverified commit.
+func (p *GiteaPlugin) isVerifiedCommit(repository, commit string) bool {
+	verification, err := p.Scanner.FetchCommitVerification(repository, commit)
+	if err != nil {
+		p.Log.Warn(fmt.Sprintf("treating commit %s in %s as unverified - %s", commit, repository, err))
+		return false
+	}
+	return verification.Verified
+}
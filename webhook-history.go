@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+func NewWebhookHistory(size int) *WebhookHistory {
+	return &WebhookHistory{
+		size: size,
+	}
+}
+
+// WebhookHistory remembers recently accepted webhook deliveries,
+// so that duplicate deliveries can be suppressed and past deliveries can be replayed.
+type WebhookHistory struct {
+	lock       sync.Mutex
+	size       int
+	deliveries []WebhookDelivery
+}
+
+type WebhookDelivery struct {
+	ID      string
+	Hash    string
+	Event   string
+	Time    time.Time
+	Options map[string]string
+	Data    []byte
+}
+
+// NewWebhookDelivery creates a delivery record for a webhook payload.
+// If no delivery ID is provided, the payload's hash is used instead.
+func NewWebhookDelivery(id, event string, options map[string]string, data []byte) WebhookDelivery {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if id == "" {
+		id = hash[:16]
+	}
+
+	return WebhookDelivery{
+		ID:      id,
+		Hash:    hash,
+		Event:   event,
+		Time:    time.Now(),
+		Options: options,
+		Data:    data,
+	}
+}
+
+// Add records a delivery.
+// If the delivery's ID or payload is already known, it is not recorded and false is returned.
+func (h *WebhookHistory) Add(delivery WebhookDelivery) bool {
+	if h.size <= 0 {
+		return true
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, known := range h.deliveries {
+		if known.ID == delivery.ID || known.Hash == delivery.Hash {
+			return false
+		}
+	}
+
+	h.deliveries = append(h.deliveries, delivery)
+	if len(h.deliveries) > h.size {
+		h.deliveries = h.deliveries[len(h.deliveries)-h.size:]
+	}
+
+	return true
+}
+
+// Remove forgets a recorded delivery.
+func (h *WebhookHistory) Remove(id string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for i, delivery := range h.deliveries {
+		if delivery.ID == id {
+			h.deliveries = append(h.deliveries[:i], h.deliveries[i+1:]...)
+			return
+		}
+	}
+}
+
+// Get returns a recorded delivery by its ID.
+func (h *WebhookHistory) Get(id string) (WebhookDelivery, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, delivery := range h.deliveries {
+		if delivery.ID == id {
+			return delivery, true
+		}
+	}
+
+	return WebhookDelivery{}, false
+}
+
+// List returns all recorded deliveries, starting with the most recent one.
+func (h *WebhookHistory) List() []WebhookDelivery {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	result := make([]WebhookDelivery, len(h.deliveries))
+	for i, delivery := range h.deliveries {
+		result[len(result)-1-i] = delivery
+	}
+	return result
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/reeveci/reeve-lib/schema"
+)
+
+// Webhook event types, named like the X-Gitea-Event header values.
+const (
+	WEBHOOK_EVENT_PUSH          = "push"
+	WEBHOOK_EVENT_CREATE        = "create"
+	WEBHOOK_EVENT_DELETE        = "delete"
+	WEBHOOK_EVENT_RELEASE       = "release"
+	WEBHOOK_EVENT_ISSUE_COMMENT = "issue_comment"
+	WEBHOOK_EVENT_PULL_COMMENT  = "pull_request_comment"
+	WEBHOOK_EVENT_REPOSITORY    = "repository"
+)
+
+// DetectWebhookEvent determines the event type of a webhook message.
+// Request headers are not available to plugins, so the event type may be specified explicitly using the `event` option.
+// Otherwise, it is derived from the structure of the payload.
+func DetectWebhookEvent(options map[string]string, data []byte) string {
+	if event := options["event"]; event != "" {
+		return strings.ToLower(event)
+	}
+
+	var shape struct {
+		Ref        *string         `json:"ref"`
+		RefType    *string         `json:"ref_type"`
+		Sha        *string         `json:"sha"`
+		Action     *string         `json:"action"`
+		Release    json.RawMessage `json:"release"`
+		Issue      json.RawMessage `json:"issue"`
+		Comment    json.RawMessage `json:"comment"`
+		Repository json.RawMessage `json:"repository"`
+	}
+	if err := json.Unmarshal(data, &shape); err != nil {
+		return ""
+	}
+
+	switch {
+	case shape.Ref != nil && shape.RefType != nil && shape.Sha != nil:
+		return WEBHOOK_EVENT_CREATE
+
+	case shape.Ref != nil && shape.RefType != nil:
+		return WEBHOOK_EVENT_DELETE
+
+	case shape.Ref != nil:
+		return WEBHOOK_EVENT_PUSH
+
+	case shape.Issue != nil && shape.Comment != nil:
+		return WEBHOOK_EVENT_ISSUE_COMMENT
+
+	case shape.Release != nil:
+		return WEBHOOK_EVENT_RELEASE
+
+	case shape.Action != nil && shape.Repository != nil && isRepositoryPayload(data):
+		return WEBHOOK_EVENT_REPOSITORY
+
+	default:
+		return ""
+	}
+}
+
+// repositoryPayloadKeys are the only keys of repository event payloads.
+// Other events with an action (e.g. issues, pull requests, wiki pages or packages) carry additional keys describing their subject.
+var repositoryPayloadKeys = map[string]bool{
+	"action":       true,
+	"repository":   true,
+	"organization": true,
+	"sender":       true,
+}
+
+// isRepositoryPayload reports whether a payload consists of repository event keys only.
+func isRepositoryPayload(data []byte) bool {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return false
+	}
+	for key := range keys {
+		if !repositoryPayloadKeys[key] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *GiteaPlugin) HandleWebhook(message schema.Message) error {
+	event := DetectWebhookEvent(message.Options, message.Data)
+
+	delivery := NewWebhookDelivery(message.Options["delivery"], event, message.Options, message.Data)
+	if !p.Deliveries.Add(delivery) {
+		p.Log.Info(fmt.Sprintf("ignoring duplicate webhook delivery %s", delivery.ID))
+		return nil
+	}
+
+	err := p.handleWebhookEvent(event, message.Data)
+	if err != nil {
+		// allow the delivery to be retried
+		p.Deliveries.Remove(delivery.ID)
+	}
+	return err
+}
+
+// ReplayWebhook handles a recently accepted webhook delivery again.
+func (p *GiteaPlugin) ReplayWebhook(id string) error {
+	delivery, ok := p.Deliveries.Get(id)
+	if !ok {
+		return fmt.Errorf("unknown webhook delivery %s", id)
+	}
+
+	p.Log.Info(fmt.Sprintf("replaying webhook delivery %s", delivery.ID))
+	return p.handleWebhookEvent(delivery.Event, delivery.Data)
+}
+
+func (p *GiteaPlugin) handleWebhookEvent(event string, data []byte) error {
+	switch event {
+	case WEBHOOK_EVENT_PUSH:
+		return p.handlePushWebhook(data)
+
+	case WEBHOOK_EVENT_CREATE:
+		return p.handleCreateWebhook(data)
+
+	case WEBHOOK_EVENT_DELETE:
+		return p.handleDeleteWebhook(data)
+
+	case WEBHOOK_EVENT_RELEASE:
+		return p.handleReleaseWebhook(data)
+
+	case WEBHOOK_EVENT_ISSUE_COMMENT, WEBHOOK_EVENT_PULL_COMMENT:
+		return p.handleIssueCommentWebhook(data)
+
+	case WEBHOOK_EVENT_REPOSITORY:
+		return p.handleRepositoryWebhook(data)
+
+	default:
+		p.Log.Debug(fmt.Sprintf("ignoring unsupported webhook event \"%s\"", event))
+		return nil
+	}
+}
+
+func (p *GiteaPlugin) handlePushWebhook(data []byte) error {
+	var webhook Webhook
+	err := json.Unmarshal(data, &webhook)
+	if err != nil {
+		return fmt.Errorf("error parsing webhook message %s", data)
+	}
+
+	p.Scanner.Notify(webhook.Repository)
+
+	// deleted refs are reported as pushes without a new commit, these are handled by delete events instead
+	if isZeroCommit(webhook.After) {
+		p.Log.Debug(fmt.Sprintf("ignoring push to deleted ref %s of repository %s", webhook.Ref, webhook.Repository.FullName))
+		return nil
+	}
+
+	commitMessages := make([]string, 0, len(webhook.Commits)+1)
+	commitMessages = append(commitMessages, webhook.HeadCommit.Message)
+	for _, commit := range webhook.Commits {
+		commitMessages = append(commitMessages, commit.Message)
+	}
+	if hasGlobalSkipDirective(commitMessages) {
+		return nil
+	}
+
+	trigger := NewTrigger("push", webhook.Repository, webhook.Ref, webhook.HeadCommit.ID, webhook.HeadCommit.Message)
+	trigger["pusher"] = webhook.Pusher.Login
+	AddCommitDetails(trigger, webhook.CommitDetails()...)
+
+	if tag, isTag := strings.CutPrefix(webhook.Ref, "refs/tags/"); isTag {
+		if files, ok := p.collectTagFiles(webhook.Repository.FullName, tag, webhook.HeadCommit.ID); ok {
+			trigger["files"] = strings.Join(files, "\n")
+		}
+	} else {
+		trigger["files"] = strings.Join(p.collectPushFiles(webhook), "\n")
+	}
+
+	err = p.API.NotifyTriggers([]schema.Trigger{trigger})
+	if err != nil {
+		return fmt.Errorf("error notifying trigger - %s", err)
+	}
+
+	return nil
+}
+
+func (p *GiteaPlugin) handleCreateWebhook(data []byte) error {
+	var webhook RefWebhook
+	err := json.Unmarshal(data, &webhook)
+	if err != nil {
+		return fmt.Errorf("error parsing webhook message %s", data)
+	}
+
+	if webhook.Sha == "" || isZeroCommit(webhook.Sha) {
+		return nil
+	}
+
+	commitDetails, err := p.Scanner.FetchCommitDetails(webhook.Repository.FullName, webhook.Sha)
+	if err != nil {
+		return err
+	}
+
+	trigger := NewTrigger("create", webhook.Repository, fullRefName(webhook.Ref, webhook.RefType), webhook.Sha, "")
+	trigger["pusher"] = webhook.Sender.Login
+	if commitDetails != nil {
+		trigger["commitMessage"] = commitDetails.Message
+		AddCommitDetails(trigger, *commitDetails)
+	}
+
+	err = p.API.NotifyTriggers([]schema.Trigger{trigger})
+	if err != nil {
+		return fmt.Errorf("error notifying trigger - %s", err)
+	}
+
+	return nil
+}
+
+func (p *GiteaPlugin) handleDeleteWebhook(data []byte) error {
+	var webhook RefWebhook
+	err := json.Unmarshal(data, &webhook)
+	if err != nil {
+		return fmt.Errorf("error parsing webhook message %s", data)
+	}
+
+	// the deleted ref is gone, so pipelines are discovered from the default branch
+	commitResponse, err := p.Scanner.FetchCommit(webhook.Repository.FullName, webhook.Repository.DefaultBranch)
+	if err != nil {
+		return err
+	}
+	if commitResponse == nil {
+		return nil
+	}
+
+	trigger := NewTrigger("delete", webhook.Repository, fullRefName(webhook.Ref, webhook.RefType), commitResponse.Commit.ID, commitResponse.Commit.Message)
+	trigger["pusher"] = webhook.Sender.Login
+	AddCommitDetails(trigger, commitResponse.Commit)
+
+	err = p.API.NotifyTriggers([]schema.Trigger{trigger})
+	if err != nil {
+		return fmt.Errorf("error notifying trigger - %s", err)
+	}
+
+	return nil
+}
+
+func (p *GiteaPlugin) handleReleaseWebhook(data []byte) error {
+	var webhook ReleaseWebhook
+	err := json.Unmarshal(data, &webhook)
+	if err != nil {
+		return fmt.Errorf("error parsing webhook message %s", data)
+	}
+
+	switch webhook.Action {
+	case "published", "updated":
+
+	default:
+		return nil
+	}
+
+	if webhook.Release.TagName == "" {
+		return nil
+	}
+
+	tagResponse, err := p.Scanner.FetchTag(webhook.Repository.FullName, webhook.Release.TagName)
+	if err != nil {
+		return err
+	}
+	if tagResponse == nil {
+		return nil
+	}
+
+	commitDetails, err := p.Scanner.FetchCommitDetails(webhook.Repository.FullName, tagResponse.Commit.Sha)
+	if err != nil {
+		return err
+	}
+
+	assets := make([]string, len(webhook.Release.Assets))
+	for i, asset := range webhook.Release.Assets {
+		assets[i] = asset.BrowserDownloadURL
+	}
+
+	trigger := NewTrigger("release", webhook.Repository, fullRefName(webhook.Release.TagName, "tag"), tagResponse.Commit.Sha, "")
+	if commitDetails != nil {
+		trigger["commitMessage"] = commitDetails.Message
+		AddCommitDetails(trigger, *commitDetails)
+	}
+	trigger["releaseAction"] = webhook.Action
+	trigger["releaseName"] = webhook.Release.Name
+	trigger["releaseBody"] = webhook.Release.Body
+	trigger["releaseAssets"] = strings.Join(assets, "\n")
+	trigger["releasePrerelease"] = strconv.FormatBool(webhook.Release.Prerelease)
+	trigger["releaseDraft"] = strconv.FormatBool(webhook.Release.Draft)
+
+	if files, ok := p.collectTagFiles(webhook.Repository.FullName, webhook.Release.TagName, tagResponse.Commit.Sha); ok {
+		trigger["files"] = strings.Join(files, "\n")
+	}
+
+	err = p.API.NotifyTriggers([]schema.Trigger{trigger})
+	if err != nil {
+		return fmt.Errorf("error notifying trigger - %s", err)
+	}
+
+	return nil
+}
+
+func (p *GiteaPlugin) handleRepositoryWebhook(data []byte) error {
+	var webhook RepositoryWebhook
+	err := json.Unmarshal(data, &webhook)
+	if err != nil {
+		return fmt.Errorf("error parsing webhook message %s", data)
+	}
+
+	switch webhook.Action {
+	case "deleted", "archived":
+		p.Log.Info(fmt.Sprintf("repository %s was %s", webhook.Repository.FullName, webhook.Action))
+		p.Scanner.Drop(webhook.Repository)
+
+	default:
+		// this covers created and unarchived repositories,
+		// and the repository's ID is used to detect renamed or transferred repositories
+		p.Scanner.Notify(webhook.Repository)
+	}
+
+	return nil
+}
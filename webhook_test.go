@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDetectWebhookEvent(t *testing.T) {
+	tests := []struct {
+		options map[string]string
+		payload string
+		event   string
+	}{
+		{map[string]string{"event": "Push"}, `{}`, WEBHOOK_EVENT_PUSH},
+		{nil, `{"ref": "refs/heads/main", "before": "a", "after": "b", "commits": []}`, WEBHOOK_EVENT_PUSH},
+		{nil, `{"ref": "v1.0.0", "ref_type": "tag", "sha": "a"}`, WEBHOOK_EVENT_CREATE},
+		{nil, `{"ref": "feature", "ref_type": "branch", "pusher_type": "user"}`, WEBHOOK_EVENT_DELETE},
+		{nil, `{"action": "published", "release": {}, "repository": {}}`, WEBHOOK_EVENT_RELEASE},
+		{nil, `{"action": "created", "issue": {}, "comment": {}, "repository": {}}`, WEBHOOK_EVENT_ISSUE_COMMENT},
+		{nil, `{"action": "created", "repository": {}, "organization": {}, "sender": {}}`, WEBHOOK_EVENT_REPOSITORY},
+		{nil, `{"action": "opened", "number": 1, "pull_request": {}, "repository": {}, "sender": {}}`, ""},
+		{nil, `{"action": "created", "package": {}, "repository": {}, "sender": {}}`, ""},
+		{nil, `not json`, ""},
+	}
+
+	for _, test := range tests {
+		if event := DetectWebhookEvent(test.options, []byte(test.payload)); event != test.event {
+			t.Errorf("%s: expected %q, got %q", test.payload, test.event, event)
+		}
+	}
+}